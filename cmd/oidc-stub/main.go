@@ -0,0 +1,152 @@
+// oidc-stub: IdP OpenID Connect minimal utk mencoba login OIDC secara lokal.
+// Tidak ada halaman login: /authorize langsung menyetujui user dari
+// login_hint (atau STUB_EMAIL) dan redirect balik dgn code.
+//
+//	STUB_ADDR    default :9000
+//	STUB_ISSUER  default http://localhost:9000
+//	STUB_EMAIL   default dev@example.com
+//	STUB_GROUPS  grup dipisah koma, mis. "books-admins"
+//
+// API: OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=books
+// OIDC_REDIRECT_URL=http://localhost:8080/auth/oidc/callback
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+type grant struct {
+	clientID, redirectURI, nonce, challenge, email string
+	exp                                            time.Time
+}
+
+func env(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func main() {
+	addr := env("STUB_ADDR", ":9000")
+	issuer := strings.TrimRight(env("STUB_ISSUER", "http://localhost:9000"), "/")
+	defEmail := env("STUB_EMAIL", "dev@example.com")
+	var groups []string
+	if v := os.Getenv("STUB_GROUPS"); v != "" {
+		groups = strings.Split(v, ",")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+	const kid = "stub"
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	var mu sync.Mutex
+	codes := map[string]grant{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                issuer,
+			"authorization_endpoint":                issuer + "/authorize",
+			"token_endpoint":                        issuer + "/token",
+			"jwks_uri":                              issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": kid, "alg": "RS256", "use": "sig",
+			"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		redirect, err := url.Parse(q.Get("redirect_uri"))
+		if err != nil || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad authorize request", http.StatusBadRequest)
+			return
+		}
+		email := q.Get("login_hint")
+		if email == "" {
+			email = defEmail
+		}
+		code := util.RandomID()
+		mu.Lock()
+		codes[code] = grant{
+			clientID: q.Get("client_id"), redirectURI: q.Get("redirect_uri"), nonce: q.Get("nonce"),
+			challenge: q.Get("code_challenge"), email: email, exp: time.Now().Add(time.Minute),
+		}
+		mu.Unlock()
+		rq := redirect.Query()
+		rq.Set("code", code)
+		rq.Set("state", q.Get("state"))
+		redirect.RawQuery = rq.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+			return
+		}
+		clientID := r.PostForm.Get("client_id")
+		if id, _, ok := r.BasicAuth(); ok {
+			clientID, _ = url.QueryUnescape(id)
+		}
+		mu.Lock()
+		g, ok := codes[r.PostForm.Get("code")]
+		delete(codes, r.PostForm.Get("code"))
+		mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || time.Now().After(g.exp) || g.clientID != clientID ||
+			g.redirectURI != r.PostForm.Get("redirect_uri") || b64(sum[:]) != g.challenge {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+			return
+		}
+		now := time.Now()
+		t := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss": issuer, "aud": g.clientID, "sub": "stub|" + g.email, "nonce": g.nonce,
+			"email": g.email, "email_verified": true, "preferred_username": strings.Split(g.email, "@")[0],
+			"groups": groups, "iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(),
+		})
+		t.Header["kid"] = kid
+		idToken, err := t.SignedString(key)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "server_error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"access_token": util.RandomID(), "token_type": "Bearer", "expires_in": 300, "id_token": idToken,
+		})
+	})
+
+	log.Println("oidc stub listening on", addr, "issuer", issuer)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Entry = satu baris audit_log.
+type Entry struct {
+	ID         int64          `json:"id"`
+	ActorID    string         `json:"actorId,omitempty"` // "" = sistem
+	Action     string         `json:"action"`            // mis. "user.roles.update"
+	TargetType string         `json:"targetType,omitempty"`
+	TargetID   string         `json:"targetId,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+// Execer = pool atau tx; audit ditulis di tx yang sama dengan perubahannya
+// supaya tidak ada perubahan tanpa jejak.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func Record(ctx context.Context, db Execer, e Entry) error {
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+	if e.Details == nil {
+		details = []byte("{}")
+	}
+	var actor *string
+	if e.ActorID != "" {
+		actor = &e.ActorID
+	}
+	_, err = db.Exec(ctx,
+		`INSERT INTO audit_log (actor_id, action, target_type, target_id, details) VALUES ($1, $2, $3, $4, $5)`,
+		actor, e.Action, e.TargetType, e.TargetID, details)
+	return err
+}
+
+// Filter utk List; field kosong = tidak difilter.
+type Filter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     int64 // id < Before (paging mundur)
+	Limit      int
+}
+
+type Log struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGLog(pool *pgxpool.Pool) *Log { return &Log{pool: pool} }
+
+func (l *Log) Record(e Entry) error { return Record(context.Background(), l.pool, e) }
+
+// List: terbaru dulu.
+func (l *Log) List(f Filter) ([]Entry, error) {
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	where, args := "WHERE TRUE", []any{}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where += " AND " + cond + " $" + itoa(len(args))
+	}
+	if f.ActorID != "" {
+		add("actor_id =", f.ActorID)
+	}
+	if f.Action != "" {
+		add("action =", f.Action)
+	}
+	if f.TargetType != "" {
+		add("target_type =", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id =", f.TargetID)
+	}
+	if f.Before > 0 {
+		add("id <", f.Before)
+	}
+	args = append(args, f.Limit)
+	rows, err := l.pool.Query(context.Background(),
+		`SELECT id, COALESCE(actor_id, ''), action, target_type, target_id, details, created_at
+		 FROM audit_log `+where+` ORDER BY id DESC LIMIT $`+itoa(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Entry, error) {
+		var e Entry
+		err := row.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.Details, &e.CreatedAt)
+		return e, err
+	})
+}
+
+func itoa(i int) string { return strconv.Itoa(i) }
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// GET /admin/audit?actorId=&action=&targetType=&targetId=&before=&limit=
+func (l *Log) HandleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := Filter{
+		ActorID: q.Get("actorId"), Action: q.Get("action"),
+		TargetType: q.Get("targetType"), TargetID: q.Get("targetId"),
+	}
+	f.Before, _ = strconv.ParseInt(q.Get("before"), 10, 64)
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	list, err := l.List(f)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err != nil {
+		log.Printf("[audit.List] error: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]any{"status": "error"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"entries": list}})
+}
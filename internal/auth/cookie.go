@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionModeHeader: klien browser minta token via cookie ("cookie").
+	SessionModeHeader = "X-Session-Mode"
+	// CSRFHeader: salinan nilai cookie CSRF utk method yang mengubah data.
+	CSRFHeader = "X-CSRF-Token"
+
+	ctxViaCookie ctxKey = "viaCookie"
+)
+
+// Cookies: mode sesi browser. Access token (HttpOnly, Path=/) dan refresh
+// token (HttpOnly, Path=/auth) disimpan di cookie, sehingga tidak bisa dibaca
+// JavaScript. CSRF pakai double-submit: cookie CSRF bisa dibaca JS dan harus
+// dikirim ulang di header X-CSRF-Token.
+type Cookies struct {
+	Secure   bool
+	Domain   string
+	SameSite http.SameSite
+}
+
+// CookiesFromEnv: nil jika SESSION_COOKIES != "1". COOKIE_SECURE=0 hanya utk
+// dev lokal via http; COOKIE_SAMESITE=lax|strict (default lax).
+func CookiesFromEnv() (*Cookies, error) {
+	if os.Getenv("SESSION_COOKIES") != "1" {
+		return nil, nil
+	}
+	c := &Cookies{Secure: os.Getenv("COOKIE_SECURE") != "0", Domain: os.Getenv("COOKIE_DOMAIN"), SameSite: http.SameSiteLaxMode}
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "", "lax":
+	case "strict":
+		c.SameSite = http.SameSiteStrictMode
+	default:
+		return nil, fmt.Errorf("COOKIE_SAMESITE must be lax or strict")
+	}
+	return c, nil
+}
+
+// nama cookie: prefix __Host-/__Secure- mencegah cookie ditimpa subdomain
+// lain (penting utk double-submit); hanya berlaku jika Secure.
+func (c *Cookies) name(base string, path string) string {
+	switch {
+	case !c.Secure:
+		return base
+	case c.Domain == "" && path == "/":
+		return "__Host-" + base
+	default:
+		return "__Secure-" + base
+	}
+}
+
+func (c *Cookies) accessName() string  { return c.name("books_at", "/") }
+func (c *Cookies) refreshName() string { return c.name("books_rt", "/auth") }
+func (c *Cookies) csrfName() string    { return c.name("books_csrf", "/") }
+
+func (c *Cookies) set(w http.ResponseWriter, name, path, value string, ttl time.Duration, httpOnly bool) {
+	ck := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: c.SameSite,
+	}
+	if value == "" {
+		ck.MaxAge = -1
+	}
+	http.SetCookie(w, ck)
+}
+
+// SetSession menulis cookie sesi. login=true: token CSRF selalu baru (token
+// yang ditanam sebelum login tidak ikut terpakai); saat refresh token CSRF
+// lama dipakai ulang (tab lain tetap jalan). Mengembalikan token CSRF utk
+// dikirim juga di body.
+func (c *Cookies) SetSession(w http.ResponseWriter, r *http.Request, access, refresh string, login bool) (string, error) {
+	csrf := c.csrfCookie(r)
+	if login || csrf == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		csrf = base64.RawURLEncoding.EncodeToString(b)
+	}
+	c.set(w, c.accessName(), "/", access, AccessTTL, true)
+	c.set(w, c.refreshName(), "/auth", refresh, RefreshTTL, true)
+	c.set(w, c.csrfName(), "/", csrf, RefreshTTL, false)
+	return csrf, nil
+}
+
+// Clear menghapus semua cookie sesi (logout).
+func (c *Cookies) Clear(w http.ResponseWriter) {
+	c.set(w, c.accessName(), "/", "", 0, true)
+	c.set(w, c.refreshName(), "/auth", "", 0, true)
+	c.set(w, c.csrfName(), "/", "", 0, false)
+}
+
+func cookieValue(r *http.Request, name string) string {
+	if ck, err := r.Cookie(name); err == nil {
+		return ck.Value
+	}
+	return ""
+}
+
+func (c *Cookies) csrfCookie(r *http.Request) string { return cookieValue(r, c.csrfName()) }
+
+// RefreshToken: refresh token dari cookie ("" jika tidak ada).
+func (c *Cookies) RefreshToken(r *http.Request) string { return cookieValue(r, c.refreshName()) }
+
+func (c *Cookies) accessToken(r *http.Request) string { return cookieValue(r, c.accessName()) }
+
+// CheckCSRF: method aman selalu lolos; selain itu header X-CSRF-Token harus
+// sama dgn cookie CSRF.
+func (c *Cookies) CheckCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	want, got := c.csrfCookie(r), r.Header.Get(CSRFHeader)
+	return want != "" && subtle.ConstantTimeCompare([]byte(want), []byte(got)) == 1
+}
+
+// ViaCookie: request diautentikasi dgn cookie sesi (bukan header Authorization).
+func ViaCookie(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxViaCookie).(bool)
+	return v
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckCSRF(t *testing.T) {
+	secure := &Cookies{Secure: true, SameSite: http.SameSiteLaxMode}
+	local := &Cookies{Secure: false, SameSite: http.SameSiteLaxMode}
+	tests := []struct {
+		name   string
+		c      *Cookies
+		method string
+		cookie string // nama cookie CSRF yang dikirim
+		value  string
+		header string
+		ok     bool
+	}{
+		{"GET without token", secure, http.MethodGet, "", "", "", true},
+		{"HEAD without token", secure, http.MethodHead, "", "", "", true},
+		{"OPTIONS without token", secure, http.MethodOptions, "", "", "", true},
+		{"POST matching", secure, http.MethodPost, "__Host-books_csrf", "tok", "tok", true},
+		{"DELETE matching", secure, http.MethodDelete, "__Host-books_csrf", "tok", "tok", true},
+		{"POST no header", secure, http.MethodPost, "__Host-books_csrf", "tok", "", false},
+		{"POST no cookie", secure, http.MethodPost, "", "", "tok", false},
+		{"POST both empty", secure, http.MethodPost, "__Host-books_csrf", "", "", false},
+		{"POST mismatch", secure, http.MethodPost, "__Host-books_csrf", "tok", "other", false},
+		// tanpa prefix __Host- cookie bisa ditanam subdomain: tidak dipakai saat Secure
+		{"POST unprefixed cookie", secure, http.MethodPost, "books_csrf", "tok", "tok", false},
+		{"POST local dev", local, http.MethodPost, "books_csrf", "tok", "tok", true},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, "/books", nil)
+		if tc.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: tc.cookie, Value: tc.value})
+		}
+		if tc.header != "" {
+			r.Header.Set(CSRFHeader, tc.header)
+		}
+		if got := tc.c.CheckCSRF(r); got != tc.ok {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.ok)
+		}
+	}
+}
+
+func TestSetSessionCSRF(t *testing.T) {
+	c := &Cookies{Secure: true, SameSite: http.SameSiteLaxMode}
+	r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	r.AddCookie(&http.Cookie{Name: c.csrfName(), Value: "planted"})
+
+	// login: token CSRF yang sudah ada (mis. ditanam penyerang) tidak dipakai
+	csrf, err := c.SetSession(httptest.NewRecorder(), r, "at", "rt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csrf == "" || csrf == "planted" {
+		t.Errorf("login: got csrf %q, want a new token", csrf)
+	}
+	// refresh: token CSRF sesi dipakai ulang
+	if csrf, _ := c.SetSession(httptest.NewRecorder(), r, "at", "rt", false); csrf != "planted" {
+		t.Errorf("refresh: got csrf %q, want existing token", csrf)
+	}
+}
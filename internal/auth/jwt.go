@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -15,46 +15,57 @@ import (
 type ctxKey string
 
 const (
-	ctxUserID ctxKey = "userID"
-	ctxRoles  ctxKey = "roles"
+	ctxUserID    ctxKey = "userID"
+	ctxRoles     ctxKey = "roles"
+	ctxSessionID ctxKey = "sessionID"
 )
 
-func MustJWTSecret() []byte {
-	sec := os.Getenv("JWT_SECRET")
-	if sec == "" {
-		panic("JWT_SECRET not set")
-	}
-	return []byte(sec)
-}
+// iatPrecision: iat access token ditulis dlm ms (angka desimal, RFC 7519
+// NumericDate) supaya pencabutan per user tidak ikut mengenai token yang
+// terbit di detik yang sama sesudahnya.
+const iatPrecision = time.Millisecond
 
-func NewTokenGenerator(secret []byte) func(sub string, roles []string) (string, error) {
-	return func(sub string, roles []string) (string, error) {
+// NewTokenGenerator: access token berumur AccessTTL, ditandatangani kunci
+// aktif keyset. sid = sesi login (family refresh token); pencabutan berlaku
+// per sesi atau per user.
+func NewTokenGenerator(ks *KeySet) func(sub string, roles []string, sid string) (string, error) {
+	return func(sub string, roles []string, sid string) (string, error) {
 		claims := jwt.MapClaims{
 			"sub":   sub,
 			"roles": roles,
-			"iat":   time.Now().Unix(),
-			"exp":   time.Now().Add(15 * time.Minute).Unix(), // short TTL
+			"sid":   sid,
+			"iat":   float64(time.Now().UnixMilli()) / 1000,
+			"exp":   time.Now().Add(AccessTTL).Unix(), // short TTL
 		}
-		t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		return t.SignedString(secret)
+		return ks.Sign(claims)
 	}
 }
 
-func AuthJWT(secret []byte) func(http.Handler) http.Handler {
+// AuthJWT memvalidasi Bearer token dengan kunci dari keyset (header kid).
+// rv nil = tanpa cek pencabutan. Jika ck tidak nil, tanpa header Authorization
+// access token dibaca dari cookie sesi dan method yang mengubah data wajib
+// lolos cek CSRF.
+func AuthJWT(ks *KeySet, rv *Revocations, ck *Cookies) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authz := r.Header.Get("Authorization")
-			if !strings.HasPrefix(authz, "Bearer ") {
+			tokStr, viaCookie := "", false
+			switch {
+			case strings.HasPrefix(authz, "Bearer "):
+				tokStr = strings.TrimPrefix(authz, "Bearer ")
+			case authz == "" && ck != nil:
+				tokStr = ck.accessToken(r)
+				viaCookie = tokStr != ""
+			}
+			if tokStr == "" {
 				http.Error(w, "missing bearer token", http.StatusUnauthorized)
 				return
 			}
-			tokStr := strings.TrimPrefix(authz, "Bearer ")
-			tok, err := jwt.Parse(tokStr, func(t *jwt.Token) (any, error) {
-				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method")
-				}
-				return secret, nil
-			})
+			if viaCookie && !ck.CheckCSRF(r) {
+				http.Error(w, "csrf token mismatch", http.StatusForbidden)
+				return
+			}
+			tok, err := ks.Parse(tokStr)
 			if err != nil || !tok.Valid {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
@@ -64,6 +75,24 @@ func AuthJWT(secret []byte) func(http.Handler) http.Handler {
 				http.Error(w, "bad token", http.StatusUnauthorized)
 				return
 			}
+			// token ber-typ (mis. mfa pending) bukan access token
+			if typ, _ := claims["typ"].(string); typ != "" {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			sid, _ := claims["sid"].(string)
+			if rv != nil {
+				// dibaca langsung (bukan GetIssuedAt) supaya ms tidak hilang
+				// karena pembulatan float saat parsing NumericDate
+				var iat time.Time
+				if v, ok := claims["iat"].(float64); ok {
+					iat = time.UnixMilli(int64(math.Round(v * 1000)))
+				}
+				if rv.IsRevoked(sid, fmt.Sprint(claims["sub"]), iat) {
+					http.Error(w, "token revoked", http.StatusUnauthorized)
+					return
+				}
+			}
 			uid := fmt.Sprint(claims["sub"])
 			var roles []string
 			if rs, ok := claims["roles"].([]any); ok {
@@ -73,6 +102,10 @@ func AuthJWT(secret []byte) func(http.Handler) http.Handler {
 			}
 			ctx := context.WithValue(r.Context(), ctxUserID, uid)
 			ctx = context.WithValue(ctx, ctxRoles, roles)
+			ctx = context.WithValue(ctx, ctxSessionID, sid)
+			if viaCookie {
+				ctx = context.WithValue(ctx, ctxViaCookie, true)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -109,4 +142,28 @@ func UserIDFromCtx(ctx context.Context) (string, error) {
 	v := ctx.Value(ctxUserID)
 	if s, ok := v.(string); ok && s != "" { return s, nil }
 	return "", errors.New("no user in context")
-}
\ No newline at end of file
+}
+// SessionIDFromCtx: sid dari access token ("" utk token lama tanpa sid).
+func SessionIDFromCtx(ctx context.Context) string {
+	s, _ := ctx.Value(ctxSessionID).(string)
+	return s
+}
+
+func RolesFromCtx(ctx context.Context) []string {
+	switch v := ctx.Value(ctxRoles).(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, x := range v { out = append(out, fmt.Sprint(x)) }
+		return out
+	}
+	return nil
+}
+
+func HasRole(ctx context.Context, role string) bool {
+	for _, r := range RolesFromCtx(ctx) {
+		if r == role { return true }
+	}
+	return false
+}
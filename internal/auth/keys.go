@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// kid utk secret HS256 (JWT_SECRET); tidak pernah dipublikasikan di JWKS.
+const hmacKID = "hs256"
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key = satu kunci di keyset. Kunci privat bisa menandatangani; kunci yang
+// hanya punya public key (file *.pub.pem) dipakai utk verifikasi saja,
+// misal kunci lama selama masa rotasi.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   any // *rsa.PrivateKey | *ecdsa.PrivateKey | ed25519.PrivateKey | []byte; nil = verify-only
+	verify any // *rsa.PublicKey | *ecdsa.PublicKey | ed25519.PublicKey | []byte
+}
+
+// KeySet = kunci verifikasi aktif + satu kunci penandatangan.
+type KeySet struct {
+	dir    string
+	secret []byte
+	kid    string // JWT_SIGNING_KID; "" = pilih otomatis
+
+	// Issuer/Audience: klaim iss/aud yang ditulis Sign dan wajib cocok di Parse.
+	Issuer   string
+	Audience string
+	// HS256Until: jika JWT_KEYS_DIR dipakai, token HS256 lama hanya diterima
+	// sampai saat ini (zero = langsung ditolak).
+	HS256Until time.Time
+
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	signing *Key
+}
+
+// MustKeySet membangun keyset dari env:
+//
+//	JWT_KEYS_DIR     direktori *.pem (kid = nama file tanpa .pem / .pub.pem)
+//	JWT_SIGNING_KID  kid utk menandatangani (default: kid privat terakhir urut nama)
+//	JWT_SECRET       HS256; dipakai menandatangani jika JWT_KEYS_DIR kosong
+//	JWT_HS256_UNTIL  RFC 3339; jika JWT_KEYS_DIR diisi, token HS256 lama masih
+//	                 diterima sampai saat ini (default: langsung ditolak)
+//	JWT_ISSUER       klaim iss (default books-api)
+//	JWT_AUDIENCE     klaim aud (default books-api)
+func MustKeySet() *KeySet {
+	ks, err := NewKeySet(os.Getenv("JWT_KEYS_DIR"), []byte(os.Getenv("JWT_SECRET")), os.Getenv("JWT_SIGNING_KID"))
+	if err != nil {
+		panic(err)
+	}
+	if v := os.Getenv("JWT_HS256_UNTIL"); v != "" {
+		if ks.HS256Until, err = time.Parse(time.RFC3339, v); err != nil {
+			panic(fmt.Errorf("JWT_HS256_UNTIL: %w", err))
+		}
+	}
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		ks.Issuer = v
+	}
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		ks.Audience = v
+	}
+	return ks
+}
+
+const defaultIssuer = "books-api"
+
+func NewKeySet(dir string, secret []byte, signingKID string) (*KeySet, error) {
+	ks := &KeySet{dir: dir, secret: secret, kid: signingKID, Issuer: defaultIssuer, Audience: defaultIssuer}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload membaca ulang direktori kunci (rotasi tanpa restart, mis. saat SIGHUP).
+// Jika gagal, keyset lama tetap dipakai.
+func (ks *KeySet) Reload() error {
+	keys := map[string]*Key{}
+	var hmacKey *Key
+	if len(ks.secret) > 0 {
+		hmacKey = &Key{ID: hmacKID, Method: jwt.SigningMethodHS256, sign: ks.secret, verify: ks.secret}
+		keys[hmacKID] = hmacKey
+	}
+	if ks.dir != "" {
+		paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			k, err := loadKeyFile(p)
+			if err == nil && k.ID == hmacKID {
+				err = errors.New("kid " + hmacKID + " is reserved")
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", filepath.Base(p), err)
+			}
+			// kid.pem (privat) menang atas kid.pub.pem
+			if old, ok := keys[k.ID]; ok && (old.sign != nil || k.sign == nil) {
+				continue
+			}
+			keys[k.ID] = k
+		}
+	}
+
+	var signing *Key
+	switch {
+	case ks.kid != "":
+		signing = keys[ks.kid]
+		if signing == nil || signing.sign == nil {
+			return fmt.Errorf("signing key %q not found", ks.kid)
+		}
+	case ks.dir != "":
+		var kids []string
+		for kid, k := range keys {
+			if k.sign != nil && k != hmacKey {
+				kids = append(kids, kid)
+			}
+		}
+		if len(kids) == 0 {
+			return errors.New("no private key in " + ks.dir)
+		}
+		sort.Strings(kids)
+		signing = keys[kids[len(kids)-1]]
+	default:
+		signing = hmacKey
+	}
+	if signing == nil {
+		return errors.New("JWT_SECRET or JWT_KEYS_DIR must be set")
+	}
+
+	ks.mu.Lock()
+	ks.keys, ks.signing = keys, signing
+	ks.mu.Unlock()
+	return nil
+}
+
+func loadKeyFile(path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	base := filepath.Base(path)
+	kid := strings.TrimSuffix(strings.TrimSuffix(base, ".pem"), ".pub")
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{ID: kid}
+	if s, ok := parsed.(crypto.Signer); ok {
+		k.sign, parsed = s, s.Public()
+	}
+	switch pub := parsed.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		k.Method, k.verify = jwt.SigningMethodRS256, pub
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported (ES256)")
+		}
+		k.Method, k.verify = jwt.SigningMethodES256, pub
+	case ed25519.PublicKey:
+		k.Method, k.verify = jwt.SigningMethodEdDSA, pub
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return k, nil
+}
+
+// Sign menandatangani claims dengan kunci aktif, mengisi iss/aud dan header kid.
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"], claims["aud"] = ks.Issuer, ks.Audience
+	ks.mu.RLock()
+	k := ks.signing
+	ks.mu.RUnlock()
+	t := jwt.NewWithClaims(k.Method, claims)
+	t.Header["kid"] = k.ID
+	return t.SignedString(k.sign)
+}
+
+// Keyfunc utk jwt.Parse: pilih kunci dari header kid (tanpa kid = HS256
+// lama) dan tolak token yang alg-nya tidak cocok dengan kunci tsb.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = hmacKID
+	}
+	ks.mu.RLock()
+	k := ks.keys[kid]
+	ks.mu.RUnlock()
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	// setelah pindah ke kunci asimetris, HS256 hanya selama masa transisi
+	if kid == hmacKID && ks.dir != "" && !time.Now().Before(ks.HS256Until) {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return k.verify, nil
+}
+
+// alg yang diterima parser
+var validMethods = []string{"HS256", "RS256", "ES256", "EdDSA"}
+
+// Parse memverifikasi tanda tangan, exp, iss dan aud token.
+func (ks *KeySet) Parse(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(validMethods),
+		jwt.WithIssuer(ks.Issuer), jwt.WithAudience(ks.Audience))
+}
+
+// JWK = satu public key dalam format RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// JWKS: semua public key (kunci HS256 tidak ikut), urut kid.
+func (ks *KeySet) JWKS() []JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	out := []JWK{}
+	for _, k := range ks.keys {
+		j := JWK{Kid: k.ID, Alg: k.Method.Alg(), Use: "sig"}
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			j.Kty, j.N, j.E = "RSA", b64(pub.N.Bytes()), b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			x, y := make([]byte, 32), make([]byte, 32)
+			pub.X.FillBytes(x)
+			pub.Y.FillBytes(y)
+			j.Kty, j.Crv, j.X, j.Y = "EC", "P-256", b64(x), b64(y)
+		case ed25519.PublicKey:
+			j.Kty, j.Crv, j.X = "OKP", "Ed25519", b64(pub)
+		default:
+			continue
+		}
+		out = append(out, j)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Kid < out[j].Kid })
+	return out
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+const (
+	// MFATTL: umur token "mfa pending" antara password dan kode 2FA.
+	MFATTL       = 5 * time.Minute
+	mfaTokenType = "mfa"
+)
+
+var ErrMFATokenInvalid = errors.New("invalid or expired mfa token")
+
+// MFATokens menerbitkan dan memeriksa token "mfa pending". Token ini
+// ditandatangani keyset yang sama tapi diberi typ=mfa, sehingga AuthJWT
+// tidak menerimanya sebagai access token.
+//
+// Kode salah tidak dihitung di sini (login ulang = token baru) tapi per user
+// di DB lewat users.Repo.LoginFailed, sama dgn password salah.
+//
+// Batasan: daftar jti terpakai hanya di memori proses ini. Dgn beberapa
+// instance atau sesudah restart, token yang sudah dipakai bisa diterima lagi
+// selama belum exp (maks MFATTL); tetap perlu kode 2FA baru karena step TOTP
+// dan recovery code ditandai terpakai di DB. Jalankan satu instance atau
+// pasang sticky session bila itu tidak cukup.
+type MFATokens struct {
+	ks *KeySet
+
+	mu   sync.Mutex
+	used map[string]time.Time // jti sudah dipakai -> exp
+}
+
+func NewMFATokens(ks *KeySet) *MFATokens {
+	return &MFATokens{ks: ks, used: map[string]time.Time{}}
+}
+
+func (m *MFATokens) Issue(sub string) (string, error) {
+	now := time.Now()
+	return m.ks.Sign(jwt.MapClaims{
+		"sub": sub,
+		"typ": mfaTokenType,
+		"jti": util.RandomID(),
+		"iat": now.Unix(),
+		"exp": now.Add(MFATTL).Unix(),
+	})
+}
+
+// Verify mengembalikan sub & jti dari token pending yang belum dipakai.
+func (m *MFATokens) Verify(token string) (sub, jti string, err error) {
+	tok, err := m.ks.Parse(token)
+	if err != nil || !tok.Valid {
+		return "", "", ErrMFATokenInvalid
+	}
+	claims, _ := tok.Claims.(jwt.MapClaims)
+	typ, _ := claims["typ"].(string)
+	sub, _ = claims["sub"].(string)
+	jti, _ = claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if typ != mfaTokenType || sub == "" || jti == "" || err != nil || exp == nil {
+		return "", "", ErrMFATokenInvalid
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for k, e := range m.used {
+		if now.After(e) {
+			delete(m.used, k)
+		}
+	}
+	if _, ok := m.used[jti]; ok {
+		return "", "", ErrMFATokenInvalid
+	}
+	return sub, jti, nil
+}
+
+// Done: token pending (jti dari Verify) sudah dipakai utk login, jangan
+// diterima lagi.
+func (m *MFATokens) Done(jti string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used[jti] = time.Now().Add(MFATTL)
+}
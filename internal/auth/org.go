@@ -0,0 +1,29 @@
+package auth
+
+import "context"
+
+const (
+	ctxOrgID   ctxKey = "orgID"
+	ctxOrgRole ctxKey = "orgRole"
+)
+
+// WithOrg: organisasi aktif pemanggil + role keanggotaannya di org tsb.
+func WithOrg(ctx context.Context, orgID, role string) context.Context {
+	ctx = context.WithValue(ctx, ctxOrgID, orgID)
+	return context.WithValue(ctx, ctxOrgRole, role)
+}
+
+// OrgIDFromCtx: org aktif ("" = ruang pribadi).
+func OrgIDFromCtx(ctx context.Context) string {
+	s, _ := ctx.Value(ctxOrgID).(string)
+	return s
+}
+
+// OrgRoleFromCtx: role pemanggil di org aktif; ok=false tanpa org aktif.
+func OrgRoleFromCtx(ctx context.Context) (string, bool) {
+	if OrgIDFromCtx(ctx) == "" {
+		return "", false
+	}
+	s, _ := ctx.Value(ctxOrgRole).(string)
+	return s, true
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+// PATPrefix membedakan personal access token dari JWT di header
+// Authorization (dan memudahkan secret scanner mengenalinya).
+const PATPrefix = "bkp_"
+
+const (
+	ctxTokenID ctxKey = "patID"
+	ctxScopes  ctxKey = "scopes"
+)
+
+var (
+	ErrPATInvalid  = errors.New("invalid personal access token")
+	ErrPATNotFound = errors.New("token not found")
+)
+
+// PAT = personal access token milik user; Scopes = izin maksimum token
+// (irisan dgn izin role user saat dipakai).
+type PAT struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // beberapa karakter awal, utk dikenali user
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// PATStore: token disimpan sebagai hash, plaintext hanya dikembalikan Create.
+type PATStore interface {
+	Create(userID, name string, scopes []string, expiresAt time.Time) (PAT, string, error)
+	// List: token aktif (belum dicabut/kedaluwarsa) milik user.
+	List(userID string) ([]PAT, error)
+	Revoke(userID, id string) error
+	// Lookup memvalidasi token + mencatat last_used_at; roles = role user saat ini.
+	Lookup(token string) (p PAT, roles []string, err error)
+}
+
+type pgPATStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGPATStore(pool *pgxpool.Pool) PATStore { return &pgPATStore{pool: pool} }
+
+func (s *pgPATStore) Create(userID, name string, scopes []string, expiresAt time.Time) (PAT, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return PAT{}, "", err
+	}
+	token := PATPrefix + base64.RawURLEncoding.EncodeToString(b)
+	p := PAT{
+		ID: util.RandomID(), UserID: userID, Name: name, Prefix: token[:len(PATPrefix)+6],
+		Scopes: scopes, ExpiresAt: expiresAt, CreatedAt: time.Now(),
+	}
+	_, err := s.pool.Exec(context.Background(),
+		`INSERT INTO personal_access_tokens (id, user_id, name, prefix, token_hash, scopes, expires_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		p.ID, userID, name, p.Prefix, hashRefresh(token), scopes, expiresAt, p.CreatedAt)
+	if err != nil {
+		return PAT{}, "", err
+	}
+	return p, token, nil
+}
+
+func (s *pgPATStore) List(userID string) ([]PAT, error) {
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		 FROM personal_access_tokens
+		 WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[PAT])
+}
+
+func (s *pgPATStore) Revoke(userID, id string) error {
+	tag, err := s.pool.Exec(context.Background(),
+		`UPDATE personal_access_tokens SET revoked_at = NOW()
+		 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrPATNotFound
+	}
+	return err
+}
+
+func (s *pgPATStore) Lookup(token string) (PAT, []string, error) {
+	ctx := context.Background()
+	var p PAT
+	var roles []string
+	err := s.pool.QueryRow(ctx,
+		`SELECT t.id, t.user_id, t.name, t.prefix, t.scopes, t.expires_at, t.last_used_at, t.created_at,
+		        COALESCE((SELECT array_agg(ur.role ORDER BY ur.role) FROM user_roles ur WHERE ur.user_id = t.user_id), '{}')
+		 FROM personal_access_tokens t JOIN users u ON u.id = t.user_id
+		 WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > NOW()
+		   AND u.suspended_at IS NULL AND u.deleted_at IS NULL`, hashRefresh(token),
+	).Scan(&p.ID, &p.UserID, &p.Name, &p.Prefix, &p.Scopes, &p.ExpiresAt, &p.LastUsedAt, &p.CreatedAt, &roles)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return PAT{}, nil, ErrPATInvalid
+	}
+	if err != nil {
+		return PAT{}, nil, err
+	}
+	// last_used_at cukup presisi per menit; hemat write utk skrip yang sering memanggil
+	if p.LastUsedAt == nil || time.Since(*p.LastUsedAt) > time.Minute {
+		if _, err := s.pool.Exec(ctx,
+			`UPDATE personal_access_tokens SET last_used_at = NOW() WHERE id = $1`, p.ID); err != nil {
+			log.Printf("[auth.PAT] last used update: %v", err)
+		}
+	}
+	return p, roles, nil
+}
+
+// patFromRequest: token dari X-API-Key atau Bearer dgn PATPrefix.
+func patFromRequest(r *http.Request) string {
+	if k := strings.TrimSpace(r.Header.Get("X-API-Key")); k != "" {
+		return k
+	}
+	if t, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(t, PATPrefix) {
+		return t
+	}
+	return ""
+}
+
+// WithPATs: middleware autentikasi yang menerima personal access token
+// (X-API-Key atau Bearer bkp_...) dan meneruskan selain itu ke jwtAuth.
+// Scope token membatasi izin (lihat ScopesFromCtx).
+func WithPATs(pats PATStore, jwtAuth func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		viaJWT := jwtAuth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := patFromRequest(r)
+			if token == "" {
+				viaJWT.ServeHTTP(w, r)
+				return
+			}
+			p, roles, err := pats.Lookup(token)
+			if err == ErrPATInvalid {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Printf("[auth.WithPATs] lookup error: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			ctx := context.WithValue(r.Context(), ctxUserID, p.UserID)
+			ctx = context.WithValue(ctx, ctxRoles, roles)
+			ctx = context.WithValue(ctx, ctxTokenID, p.ID)
+			ctx = context.WithValue(ctx, ctxScopes, p.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TokenIDFromCtx: id PAT jika request diautentikasi dgn PAT ("" utk JWT).
+func TokenIDFromCtx(ctx context.Context) string {
+	s, _ := ctx.Value(ctxTokenID).(string)
+	return s
+}
+
+// ScopesFromCtx: scope PAT; ok=false utk JWT (tidak dibatasi scope).
+func ScopesFromCtx(ctx context.Context) ([]string, bool) {
+	s, ok := ctx.Value(ctxScopes).([]string)
+	return s, ok
+}
+
+// DenyPAT: route hanya utk login interaktif (JWT), mis. mengelola token
+// atau keamanan akun.
+func DenyPAT(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if TokenIDFromCtx(r.Context()) != "" {
+			http.Error(w, "not allowed with a personal access token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
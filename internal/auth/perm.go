@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+const ctxPerms ctxKey = "permissions"
+
+// WithPermissions menyimpan izin pemanggil (hasil resolusi role, lihat
+// rbac.Resolver.Attach) di context.
+func WithPermissions(ctx context.Context, perms map[string]bool) context.Context {
+	return context.WithValue(ctx, ctxPerms, perms)
+}
+
+func HasPermission(ctx context.Context, perm string) bool {
+	perms, _ := ctx.Value(ctxPerms).(map[string]bool)
+	return perms[perm]
+}
+
+// RequirePermission: 403 jika pemanggil tidak punya izin perm.
+// Harus dipasang setelah AuthJWT dan rbac.Resolver.Attach.
+func RequirePermission(perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r.Context(), perm) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
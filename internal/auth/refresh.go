@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+const (
+	AccessTTL  = 15 * time.Minute
+	RefreshTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrRefreshInvalid = errors.New("invalid refresh token")
+	// ErrRefreshReused: token yang sudah dirotasi dipakai lagi; seluruh
+	// family sudah dicabut.
+	ErrRefreshReused   = errors.New("refresh token reused")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionInfo dicatat saat login utk ditampilkan di daftar sesi.
+type SessionInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session = satu login (satu family refresh token).
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	Current    bool      `json:"current"`
+}
+
+// RefreshGrant: refresh token baru beserta pemilik dan sesinya.
+type RefreshGrant struct {
+	Token     string
+	UserID    string
+	SessionID string
+}
+
+// RefreshStore menyimpan refresh token (opaque, hanya hash-nya yang disimpan).
+// Family = rantai token hasil rotasi sejak satu login = satu sesi.
+type RefreshStore interface {
+	// Issue membuat sesi baru + token pertamanya (login).
+	Issue(userID string, info SessionInfo) (RefreshGrant, error)
+	// Rotate menukar token dengan token baru di sesi yang sama. Pada
+	// ErrRefreshReused, UserID/SessionID grant tetap diisi.
+	Rotate(token string) (RefreshGrant, error)
+	// Revoke mencabut sesi pemilik token (logout). Token tak dikenal diabaikan
+	// (sessionID kosong).
+	Revoke(token string) (sessionID string, err error)
+	// Sessions: sesi user yang masih bisa di-refresh, terbaru dulu.
+	Sessions(userID string) ([]Session, error)
+	RevokeSession(userID, sessionID string) error
+	// RevokeUser mencabut semua sesi + personal access token user.
+	RevokeUser(userID string) error
+}
+
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefresh(token), nil
+}
+
+func hashRefresh(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type pgRefreshStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGRefreshStore(pool *pgxpool.Pool) RefreshStore {
+	return &pgRefreshStore{pool: pool}
+}
+
+func insertRefresh(ctx context.Context, tx pgx.Tx, userID, familyID string, parentID *string) (string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (id, user_id, family_id, parent_id, token_hash, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		util.RandomID(), userID, familyID, parentID, hash, time.Now().Add(RefreshTTL))
+	return token, err
+}
+
+// revokeFamily mencabut sesi beserta semua refresh token-nya.
+func revokeFamily(ctx context.Context, tx pgx.Tx, familyID string) error {
+	if _, err := tx.Exec(ctx,
+		`UPDATE auth_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, familyID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	return err
+}
+
+func (s *pgRefreshStore) Issue(userID string, info SessionInfo) (RefreshGrant, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return RefreshGrant{}, err
+	}
+	defer tx.Rollback(ctx)
+	// bersihkan sesi yang semua token-nya sudah kedaluwarsa
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM auth_sessions s WHERE s.user_id = $1
+		   AND NOT EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.family_id = s.id AND t.expires_at > NOW())`,
+		userID); err != nil {
+		return RefreshGrant{}, err
+	}
+	g := RefreshGrant{UserID: userID, SessionID: util.RandomID()}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO auth_sessions (id, user_id, user_agent, ip) VALUES ($1, $2, $3, $4)`,
+		g.SessionID, userID, info.UserAgent, info.IP); err != nil {
+		return RefreshGrant{}, err
+	}
+	if g.Token, err = insertRefresh(ctx, tx, userID, g.SessionID, nil); err != nil {
+		return RefreshGrant{}, err
+	}
+	return g, tx.Commit(ctx)
+}
+
+func (s *pgRefreshStore) Rotate(token string) (RefreshGrant, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return RefreshGrant{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		id                string
+		g                 RefreshGrant
+		expiresAt         time.Time
+		usedAt, revokedAt *time.Time
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT id, user_id, family_id, expires_at, used_at, revoked_at
+		 FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, hashRefresh(token),
+	).Scan(&id, &g.UserID, &g.SessionID, &expiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RefreshGrant{}, ErrRefreshInvalid
+	}
+	if err != nil {
+		return RefreshGrant{}, err
+	}
+	switch {
+	case revokedAt != nil || time.Now().After(expiresAt):
+		return RefreshGrant{}, ErrRefreshInvalid
+	case usedAt != nil:
+		// token lama dipakai ulang: kemungkinan dicuri -> cabut seluruh family
+		if err := revokeFamily(ctx, tx, g.SessionID); err != nil {
+			return RefreshGrant{}, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return RefreshGrant{}, err
+		}
+		return g, ErrRefreshReused
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, id); err != nil {
+		return RefreshGrant{}, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE auth_sessions SET last_used_at = NOW() WHERE id = $1`, g.SessionID); err != nil {
+		return RefreshGrant{}, err
+	}
+	if g.Token, err = insertRefresh(ctx, tx, g.UserID, g.SessionID, &id); err != nil {
+		return RefreshGrant{}, err
+	}
+	return g, tx.Commit(ctx)
+}
+
+func (s *pgRefreshStore) Revoke(token string) (string, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	var sid string
+	err = tx.QueryRow(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1`, hashRefresh(token)).Scan(&sid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := revokeFamily(ctx, tx, sid); err != nil {
+		return "", err
+	}
+	return sid, tx.Commit(ctx)
+}
+
+func (s *pgRefreshStore) Sessions(userID string) ([]Session, error) {
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT s.id, s.user_agent, s.ip, s.created_at, s.last_used_at
+		 FROM auth_sessions s
+		 WHERE s.user_id = $1 AND s.revoked_at IS NULL
+		   AND EXISTS (SELECT 1 FROM refresh_tokens t
+		               WHERE t.family_id = s.id AND t.used_at IS NULL
+		                 AND t.revoked_at IS NULL AND t.expires_at > NOW())
+		 ORDER BY s.last_used_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Session{}
+	for rows.Next() {
+		var ss Session
+		if err := rows.Scan(&ss.ID, &ss.UserAgent, &ss.IP, &ss.CreatedAt, &ss.LastUsedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ss)
+	}
+	return out, rows.Err()
+}
+
+func (s *pgRefreshStore) RevokeSession(userID, sessionID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var revokedAt *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT revoked_at FROM auth_sessions WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		sessionID, userID).Scan(&revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && revokedAt != nil) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := revokeFamily(ctx, tx, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *pgRefreshStore) RevokeUser(userID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx,
+		`UPDATE auth_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE personal_access_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// jenis pencabutan (kolom token_revocations.kind). Tiap access token punya
+// sid, jadi mencabut satu token = mencabut sesinya (RevokeSID).
+const (
+	RevokeSID  = "sid"  // semua access token satu sesi
+	RevokeUser = "user" // semua access token user yang terbit sebelum revoked_at
+)
+
+// Revocations = daftar access token yang dicabut sebelum exp.
+// Sumber kebenaran di Postgres (token_revocations); AuthJWT hanya membaca
+// cache di memori. Cache disinkron berkala (utk instance lain) dan entri
+// dibuang setelah expires_at, karena token yang dicabut toh sudah kedaluwarsa.
+type Revocations struct {
+	pool *pgxpool.Pool
+
+	mu      sync.RWMutex
+	entries map[revKey]revEntry
+	since   time.Time // revoked_at terbaru yang sudah disinkron
+}
+
+type revKey struct{ kind, subject string }
+
+type revEntry struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+// NewRevocations: pool nil = hanya di memori (satu instance).
+func NewRevocations(pool *pgxpool.Pool) *Revocations {
+	return &Revocations{pool: pool, entries: map[revKey]revEntry{}}
+}
+
+// Start memuat isi tabel lalu sinkron + prune tiap interval sampai ctx selesai.
+func (rv *Revocations) Start(ctx context.Context, every time.Duration) error {
+	if err := rv.sync(ctx); err != nil {
+		return err
+	}
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := rv.sync(ctx); err != nil {
+					log.Printf("[auth.Revocations] sync error: %v", err)
+				}
+				rv.prune(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// clock skew/commit yang terlambat: ambil ulang sedikit ke belakang
+const revSyncOverlap = time.Minute
+
+func (rv *Revocations) sync(ctx context.Context) error {
+	if rv.pool == nil {
+		return nil
+	}
+	rv.mu.RLock()
+	since := rv.since.Add(-revSyncOverlap)
+	rv.mu.RUnlock()
+	rows, err := rv.pool.Query(ctx,
+		`SELECT kind, subject, revoked_at, expires_at FROM token_revocations
+		 WHERE revoked_at > $1 AND expires_at > NOW()`, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	for rows.Next() {
+		var k revKey
+		var e revEntry
+		if err := rows.Scan(&k.kind, &k.subject, &e.revokedAt, &e.expiresAt); err != nil {
+			return err
+		}
+		rv.entries[k] = e
+		if e.revokedAt.After(rv.since) {
+			rv.since = e.revokedAt
+		}
+	}
+	return rows.Err()
+}
+
+func (rv *Revocations) prune(ctx context.Context) {
+	now := time.Now()
+	rv.mu.Lock()
+	for k, e := range rv.entries {
+		if now.After(e.expiresAt) {
+			delete(rv.entries, k)
+		}
+	}
+	rv.mu.Unlock()
+	if rv.pool != nil {
+		if _, err := rv.pool.Exec(ctx, `DELETE FROM token_revocations WHERE expires_at < NOW()`); err != nil {
+			log.Printf("[auth.Revocations] prune error: %v", err)
+		}
+	}
+}
+
+// Revoke mencatat pencabutan. Cukup disimpan selama AccessTTL: token yang
+// terbit sebelum saat ini pasti sudah kedaluwarsa setelahnya.
+func (rv *Revocations) Revoke(kind, subject string) error {
+	now := time.Now()
+	e := revEntry{revokedAt: now, expiresAt: now.Add(AccessTTL)}
+	if rv.pool != nil {
+		_, err := rv.pool.Exec(context.Background(),
+			`INSERT INTO token_revocations (kind, subject, revoked_at, expires_at) VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (kind, subject) DO UPDATE SET revoked_at = EXCLUDED.revoked_at, expires_at = EXCLUDED.expires_at`,
+			kind, subject, e.revokedAt, e.expiresAt)
+		if err != nil {
+			return err
+		}
+	}
+	rv.mu.Lock()
+	rv.entries[revKey{kind, subject}] = e
+	rv.mu.Unlock()
+	return nil
+}
+
+// IsRevoked memeriksa klaim token terhadap cache (tanpa query DB).
+func (rv *Revocations) IsRevoked(sid, sub string, iat time.Time) bool {
+	rv.mu.RLock()
+	defer rv.mu.RUnlock()
+	if sid != "" {
+		if _, ok := rv.entries[revKey{RevokeSID, sid}]; ok {
+			return true
+		}
+	}
+	// iat berpresisi ms (iatPrecision): token yang terbit di ms yang sama
+	// dgn pencabutan dianggap terbit sesudahnya, supaya token baru dari
+	// ganti password/reset tidak langsung ikut dicabut.
+	if e, ok := rv.entries[revKey{RevokeUser, sub}]; ok && iat.Before(e.revokedAt.Truncate(iatPrecision)) {
+		return true
+	}
+	return false
+}
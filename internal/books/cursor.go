@@ -0,0 +1,173 @@
+package books
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor menunjuk posisi di urutan list: nilai tiap sort key dari baris
+// terakhir yang dilihat klien. Di-encode base64url supaya opaque; cursor
+// hanya berlaku utk sort yang sama dengan saat ia dibuat.
+type Cursor struct {
+	sig    string
+	values []any
+}
+
+type cursorWire struct {
+	S string            `json:"s"`
+	V []json.RawMessage `json:"v"`
+}
+
+func cursorOf(b Book, keys []SortKey) *Cursor {
+	c := &Cursor{sig: sortSig(keys), values: make([]any, len(keys))}
+	for i, k := range keys {
+		c.values[i] = sortFields[k.Field].get(b)
+	}
+	return c
+}
+
+func (c Cursor) Encode() string {
+	w := cursorWire{S: c.sig}
+	for _, v := range c.values {
+		if t, ok := v.(time.Time); ok {
+			v = t.UnixNano()
+		}
+		raw, _ := json.Marshal(v)
+		w.V = append(w.V, raw)
+	}
+	raw, _ := json.Marshal(w)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor memvalidasi cursor terhadap sort yang diminta.
+func DecodeCursor(s string, keys []SortKey) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var w cursorWire
+	if json.Unmarshal(raw, &w) != nil || w.S != sortSig(keys) || len(w.V) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{sig: w.S, values: make([]any, len(keys))}
+	for i, k := range keys {
+		var err error
+		switch sortFields[k.Field].kind {
+		case kindStr:
+			var v string
+			err = json.Unmarshal(w.V[i], &v)
+			c.values[i] = v
+		case kindInt:
+			var v int
+			err = json.Unmarshal(w.V[i], &v)
+			c.values[i] = v
+		case kindFloat:
+			var v float64
+			err = json.Unmarshal(w.V[i], &v)
+			c.values[i] = v
+		case kindTime:
+			var v int64
+			err = json.Unmarshal(w.V[i], &v)
+			c.values[i] = time.Unix(0, v)
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// cmp: posisi b relatif thd c di urutan keys (-1 sebelum, 0 sama, 1 sesudah).
+func (c Cursor) cmp(b Book, keys []SortKey) int {
+	for i, k := range keys {
+		r := compareValues(sortFields[k.Field].get(b), c.values[i])
+		if k.Desc {
+			r = -r
+		}
+		if r != 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+// paginate memotong hasil yang sudah terurut sesuai After/Before/Offset.
+// Limit <= 0 = semua.
+func paginate(items []Book, f Filter) Page {
+	keys := f.sortKeys()
+	n := f.Limit
+	if n <= 0 {
+		n = len(items)
+	}
+	pg := Page{}
+	switch {
+	case f.After != nil:
+		start := 0
+		for start < len(items) && f.After.cmp(items[start], keys) <= 0 {
+			start++
+		}
+		end := min(start+n, len(items))
+		pg.Items, pg.HasPrev, pg.HasNext = items[start:end], true, end < len(items)
+	case f.Before != nil:
+		end := 0
+		for end < len(items) && f.Before.cmp(items[end], keys) < 0 {
+			end++
+		}
+		start := max(end-n, 0)
+		pg.Items, pg.HasPrev, pg.HasNext = items[start:end], start > 0, true
+	default:
+		start := min(max(f.Offset, 0), len(items))
+		end := min(start+n, len(items))
+		pg.Items, pg.HasPrev, pg.HasNext = items[start:end], start > 0, end < len(items)
+	}
+	return pg
+}
+
+// mode hitung total
+const (
+	CountExact    = "exact"
+	CountEstimate = "estimate"
+	CountNone     = "none"
+)
+
+// Page = hasil List. Total -1 jika tidak dihitung (count=none).
+type Page struct {
+	Items   []Book
+	Total   int
+	HasNext bool
+	HasPrev bool
+}
+
+// pageLinks membangun URL next/prev dari request saat ini dan
+// mengisi header Link (RFC 8288).
+func pageLinks(w http.ResponseWriter, r *http.Request, pg Page, keys []SortKey) (next, prev string) {
+	build := func(key string, c *Cursor) string {
+		q := r.URL.Query()
+		q.Del("after")
+		q.Del("before")
+		q.Del("offset")
+		q.Set(key, c.Encode())
+		u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+		return u.String()
+	}
+	var links []string
+	if pg.HasNext && len(pg.Items) > 0 {
+		next = build("after", cursorOf(pg.Items[len(pg.Items)-1], keys))
+		links = append(links, `<`+next+`>; rel="next"`)
+	}
+	if pg.HasPrev && len(pg.Items) > 0 {
+		prev = build("before", cursorOf(pg.Items[0], keys))
+		links = append(links, `<`+prev+`>; rel="prev"`)
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	return next, prev
+}
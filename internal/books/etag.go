@@ -0,0 +1,77 @@
+package books
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func etag(version int) string { return `"` + strconv.Itoa(version) + `"` }
+
+// ifMatchVersions membaca header If-Match (RFC 9110 §13.1.1): "*" atau
+// daftar ETag dipisah koma. Weak ETag tidak pernah cocok (strong comparison)
+// sehingga dilewati; tag yang tidak bisa dipetakan ke versi juga dilewati.
+func ifMatchVersions(r *http.Request) (versions []int, star, present bool) {
+	v := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if v == "" {
+		return nil, false, false
+	}
+	for _, tag := range strings.Split(v, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true, true
+		}
+		if strings.HasPrefix(tag, "W/") || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if n, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil && n > 0 {
+			versions = append(versions, n)
+		}
+	}
+	return versions, false, true
+}
+
+// noneMatch true jika salah satu ETag di If-None-Match cocok (weak comparison).
+func noneMatch(r *http.Request, current string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// precondition mengambil versi dari If-Match dan menulis 412/428 bila perlu.
+// 0 = tanpa syarat. Jika If-Match berisi beberapa ETag, versi buku saat ini
+// dipakai bila termasuk daftar; store tetap mengecek ulang versi tsb secara
+// atomik sehingga perubahan di antaranya tetap menghasilkan 412.
+func (h *Handler) precondition(w http.ResponseWriter, r *http.Request, id string) (int, bool) {
+	versions, star, present := ifMatchVersions(r)
+	mismatch := func() (int, bool) {
+		writeJSON(w, http.StatusPreconditionFailed, map[string]any{"status": "fail", "message": "version mismatch"})
+		return 0, false
+	}
+	switch {
+	case !present && h.RequireIfMatch:
+		writeJSON(w, http.StatusPreconditionRequired, map[string]any{"status": "fail", "message": "If-Match header required"})
+		return 0, false
+	case !present || star:
+		return 0, true
+	case len(versions) == 0:
+		return mismatch()
+	case len(versions) == 1:
+		return versions[0], true
+	}
+	b, err := h.Store.Get(scope(r), id)
+	if err != nil {
+		// biar store yang menentukan 404/412
+		return versions[0], true
+	}
+	for _, v := range versions {
+		if v == b.Version {
+			return v, true
+		}
+	}
+	return mismatch()
+}
@@ -0,0 +1,338 @@
+package books
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// batas baris worksheet Excel (termasuk header)
+const maxXLSXRows = 1 << 20
+
+var errXLSXTooLarge = fmt.Errorf("xlsx supports at most %d rows", maxXLSXRows-1)
+
+// exportFormats: format -> content type, ekstensi file.
+var exportFormats = map[string][2]string{
+	"csv":    {"text/csv; charset=utf-8", "csv"},
+	"ndjson": {"application/x-ndjson", "ndjson"},
+	"xlsx":   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+}
+
+// exportWriter menulis satu baris per buku; kolom = fields.
+type exportWriter interface {
+	row(b Book) error
+	// flush mendorong buffer ke writer di bawahnya (utk streaming).
+	flush() error
+	close() error
+}
+
+func newExportWriter(format string, w io.Writer, fields []bookField) (exportWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVExport(w, fields)
+	case "ndjson":
+		return &ndjsonExport{bw: bufio.NewWriter(w), fields: fields}, nil
+	case "xlsx":
+		return newXLSXExport(w, fields)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// exportCell: representasi teks nilai field (CSV).
+func exportCell(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// sheetCell = exportCell utk CSV. Teks yang diawali = + - @ tab atau CR
+// diberi prefix ' supaya tidak dieksekusi sbg formula oleh spreadsheet.
+// XLSX tidak perlu: inline string tidak pernah dievaluasi sbg formula.
+func sheetCell(v any) string {
+	s := exportCell(v)
+	if _, ok := v.(string); ok && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type csvExport struct {
+	cw     *csv.Writer
+	fields []bookField
+	rec    []string
+}
+
+func newCSVExport(w io.Writer, fields []bookField) (*csvExport, error) {
+	e := &csvExport{cw: csv.NewWriter(w), fields: fields, rec: make([]string, len(fields))}
+	for i, f := range fields {
+		e.rec[i] = f.name
+	}
+	return e, e.cw.Write(e.rec)
+}
+
+func (e *csvExport) row(b Book) error {
+	for i, f := range e.fields {
+		e.rec[i] = sheetCell(f.val(b))
+	}
+	return e.cw.Write(e.rec)
+}
+
+func (e *csvExport) flush() error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+func (e *csvExport) close() error { return e.flush() }
+
+type ndjsonExport struct {
+	bw     *bufio.Writer
+	fields []bookField
+}
+
+func (e *ndjsonExport) row(b Book) error {
+	// map -> key urut alfabet; cukup utk NDJSON
+	obj := make(map[string]any, len(e.fields))
+	for _, f := range e.fields {
+		obj[f.name] = f.val(b)
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	e.bw.Write(raw)
+	return e.bw.WriteByte('\n')
+}
+
+func (e *ndjsonExport) flush() error { return e.bw.Flush() }
+func (e *ndjsonExport) close() error { return e.bw.Flush() }
+
+// xlsxExport menulis workbook minimal (satu sheet, inline string) langsung
+// ke zip stream, jadi tidak perlu menampung shared strings di memori.
+type xlsxExport struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	fields []bookField
+	rows   int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Books" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+)
+
+func newXLSXExport(w io.Writer, fields []bookField) (*xlsxExport, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range [][2]string{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		fw, err := zw.Create(part[0])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(fw, part[1]); err != nil {
+			return nil, err
+		}
+	}
+	// sheet terakhir: entry zip yang masih terbuka sampai close()
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &xlsxExport{zw: zw, sheet: bufio.NewWriter(fw), fields: fields}
+	e.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]any, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+	return e, e.writeRow(header)
+}
+
+func (e *xlsxExport) writeRow(vals []any) error {
+	if e.rows >= maxXLSXRows {
+		return errXLSXTooLarge
+	}
+	e.rows++
+	e.sheet.WriteString("<row>")
+	for _, v := range vals {
+		switch v := v.(type) {
+		case int:
+			e.sheet.WriteString("<c><v>" + strconv.Itoa(v) + "</v></c>")
+		case bool:
+			n := "0"
+			if v {
+				n = "1"
+			}
+			e.sheet.WriteString(`<c t="b"><v>` + n + "</v></c>")
+		default:
+			e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			// EscapeText juga mengganti karakter yang tidak valid di XML
+			if err := xml.EscapeText(e.sheet, []byte(exportCell(v))); err != nil {
+				return err
+			}
+			e.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxExport) row(b Book) error {
+	vals := make([]any, len(e.fields))
+	for i, f := range e.fields {
+		vals[i] = f.val(b)
+	}
+	return e.writeRow(vals)
+}
+
+func (e *xlsxExport) flush() error {
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zw.Flush()
+}
+
+func (e *xlsxExport) close() error {
+	e.sheet.WriteString("</sheetData></worksheet>")
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// exportFields: ?fields= / ?view=; default export = semua field.
+func exportFields(q url.Values) ([]string, []bookField, error) {
+	view := q.Get("view")
+	if view == "" {
+		view = "full"
+	}
+	names, err := ParseFields(q.Get("fields"), view)
+	if err != nil {
+		return nil, nil, err
+	}
+	if names == nil {
+		return nil, bookFieldList, nil
+	}
+	cols := make([]bookField, len(names))
+	for i, n := range names {
+		cols[i] = bookFieldByName[n]
+	}
+	return names, cols, nil
+}
+
+// parseExport membaca format + parameter filter yang sama dengan GET /books.
+// ?after= (cursor dari GET /books) memulai export setelah baris tsb.
+func parseExport(q url.Values) (format string, f Filter, cols []bookField, err error) {
+	format = q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if _, ok := exportFormats[format]; !ok {
+		return "", f, nil, errors.New("format must be csv|ndjson|xlsx")
+	}
+	if f, err = ParseFilter(q); err != nil {
+		return "", f, nil, errors.New("invalid filter or sort")
+	}
+	if f.Fields, cols, err = exportFields(q); err != nil {
+		return "", f, nil, errors.New("invalid fields or view")
+	}
+	if v := q.Get("after"); v != "" {
+		if f.Q != "" {
+			return "", f, nil, errors.New("after cursor cannot be combined with q")
+		}
+		if f.After, err = DecodeCursor(v, f.Sort); err != nil {
+			return "", f, nil, errors.New("invalid after cursor")
+		}
+	}
+	return format, f, cols, nil
+}
+
+func exportFilename(format string, t time.Time) string {
+	return "books-" + t.UTC().Format("20060102-150405") + "." + exportFormats[format][1]
+}
+
+// tiap N baris buffer di-flush ke klien
+const exportFlushEvery = 500
+
+// GET /books/export?format=csv|ndjson|xlsx&<filter GET /books>&fields=|view=&after=
+//
+// Baris di-stream langsung dari Store.Each, tidak ditampung. Jika stream
+// gagal di tengah jalan koneksi diputus (bukan 200 dengan file terpotong).
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	format, f, cols, err := parseExport(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	// export besar bisa melewati WriteTimeout server
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", exportFormats[format][0])
+	w.Header().Set("Content-Disposition", `attachment; filename="`+exportFilename(format, time.Now())+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	ew, err := newExportWriter(format, w, cols)
+	if err != nil {
+		log.Printf("[books.Export] writer error: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+	n := 0
+	err = h.Store.Each(scope(r), f, func(b Book) error {
+		if err := ew.row(b); err != nil {
+			return err
+		}
+		if n++; n%exportFlushEvery == 0 {
+			if err := ew.flush(); err != nil {
+				return err
+			}
+			_ = rc.Flush()
+		}
+		return r.Context().Err()
+	})
+	if err == nil {
+		err = ew.close()
+	}
+	if err != nil {
+		log.Printf("[books.Export] aborted after %d rows: %v", n, err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,234 @@
+package books
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+// Export job: utk library besar export ditulis ke file sementara di
+// background, lalu diunduh lewat http.ServeContent yang mendukung Range
+// (unduhan yang terputus bisa dilanjutkan, If-Range dari Last-Modified).
+// Job disimpan di memori proses; hilang saat restart.
+
+const (
+	exportJobTTL        = 24 * time.Hour // file dihapus setelah ini
+	maxRunningExportJob = 2              // per user
+)
+
+var ErrExportJobNotFound = errors.New("export job not found")
+
+type exportJob struct {
+	ID         string     `json:"id"`
+	Format     string     `json:"format"`
+	Status     string     `json:"status"` // running | done | failed | canceled
+	Rows       int        `json:"rows"`
+	Size       int64      `json:"size,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	owner    string
+	org      string
+	filename string
+	path     string
+	cancel   context.CancelFunc
+}
+
+type exportJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*exportJob
+}
+
+func newExportJobs() *exportJobs { return &exportJobs{jobs: map[string]*exportJob{}} }
+
+// prune menghapus job yang kedaluwarsa beserta filenya. mu harus dipegang.
+func (js *exportJobs) prune(now time.Time) {
+	for id, j := range js.jobs {
+		if j.FinishedAt != nil && now.Sub(*j.FinishedAt) > exportJobTTL {
+			os.Remove(j.path)
+			delete(js.jobs, id)
+		}
+	}
+}
+
+// visibleTo: job di tenant pemanggil, miliknya (admin: semua).
+func (j *exportJob) visibleTo(sc Scope) bool {
+	return j.org == sc.OrgID && (sc.Admin || j.owner == sc.UserID)
+}
+
+// get: job yang terlihat oleh pemanggil. Salinan, aman dibaca tanpa lock.
+func (js *exportJobs) get(sc Scope, id string) (exportJob, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.prune(time.Now())
+	j, ok := js.jobs[id]
+	if !ok || !j.visibleTo(sc) {
+		return exportJob{}, ErrExportJobNotFound
+	}
+	return *j, nil
+}
+
+func (js *exportJobs) finish(j *exportJob, rows int, size int64, err error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	now := time.Now()
+	j.Rows, j.Size, j.FinishedAt = rows, size, &now
+	switch {
+	case j.Status == "canceled":
+	case err != nil:
+		j.Status, j.Error = "failed", err.Error()
+	default:
+		j.Status = "done"
+	}
+	if j.Status != "done" {
+		os.Remove(j.path)
+	}
+}
+
+func jobView(j exportJob) map[string]any {
+	out := map[string]any{"job": j}
+	if j.Status == "done" {
+		out["downloadUrl"] = "/books/export/jobs/" + j.ID + "/download"
+	}
+	return out
+}
+
+// POST /books/export/jobs?format=&<parameter GET /books/export>
+func (h *Handler) CreateExportJob(w http.ResponseWriter, r *http.Request) {
+	format, f, cols, err := parseExport(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	}
+	sc := scope(r)
+
+	js := h.exports
+	js.mu.Lock()
+	now := time.Now()
+	js.prune(now)
+	running := 0
+	for _, j := range js.jobs {
+		if j.owner == sc.UserID && j.Status == "running" {
+			running++
+		}
+	}
+	if running >= maxRunningExportJob {
+		js.mu.Unlock()
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{"status": "fail", "message": "too many running export jobs"})
+		return
+	}
+	file, err := os.CreateTemp("", "books-export-*."+exportFormats[format][1])
+	if err != nil {
+		js.mu.Unlock()
+		log.Printf("[books.CreateExportJob] temp file error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &exportJob{
+		ID: util.RandomID(), Format: format, Status: "running", CreatedAt: now,
+		owner: sc.UserID, org: sc.OrgID, filename: exportFilename(format, now), path: file.Name(), cancel: cancel,
+	}
+	js.jobs[j.ID] = j
+	view := jobView(*j)
+	js.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		defer file.Close()
+		n := 0
+		ew, err := newExportWriter(format, file, cols)
+		if err == nil {
+			err = h.Store.Each(sc, f, func(b Book) error {
+				if err := ew.row(b); err != nil {
+					return err
+				}
+				n++
+				return ctx.Err()
+			})
+			if err == nil {
+				err = ew.close()
+			}
+		}
+		var size int64
+		if st, serr := file.Stat(); serr == nil {
+			size = st.Size()
+		}
+		if err != nil {
+			log.Printf("[books.ExportJob %s] failed after %d rows: %v", j.ID, n, err)
+		}
+		js.finish(j, n, size, err)
+	}()
+
+	w.Header().Set("Location", "/books/export/jobs/"+j.ID)
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "success", "data": view})
+}
+
+// GET /books/export/jobs/{jobId}
+func (h *Handler) ExportJob(w http.ResponseWriter, r *http.Request) {
+	j, err := h.exports.get(scope(r), chi.URLParam(r, "jobId"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "export job not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": jobView(j)})
+}
+
+// GET /books/export/jobs/{jobId}/download (mendukung Range)
+func (h *Handler) DownloadExportJob(w http.ResponseWriter, r *http.Request) {
+	j, err := h.exports.get(scope(r), chi.URLParam(r, "jobId"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "export job not found"})
+		return
+	}
+	if j.Status != "done" {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "export job is " + j.Status})
+		return
+	}
+	file, err := os.Open(j.path)
+	if err != nil {
+		log.Printf("[books.DownloadExportJob] open error: %v", err)
+		writeJSON(w, http.StatusGone, map[string]any{"status": "fail", "message": "export file is no longer available"})
+		return
+	}
+	defer file.Close()
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", exportFormats[j.Format][0])
+	w.Header().Set("Content-Disposition", `attachment; filename="`+j.filename+`"`)
+	w.Header().Set("ETag", `"`+j.ID+`"`)
+	http.ServeContent(w, r, j.filename, *j.FinishedAt, file)
+}
+
+// DELETE /books/export/jobs/{jobId}: batalkan (jika masih jalan) dan hapus file.
+func (h *Handler) DeleteExportJob(w http.ResponseWriter, r *http.Request) {
+	js := h.exports
+	sc := scope(r)
+	id := chi.URLParam(r, "jobId")
+	js.mu.Lock()
+	j, ok := js.jobs[id]
+	if !ok || !j.visibleTo(sc) {
+		js.mu.Unlock()
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "export job not found"})
+		return
+	}
+	if j.Status == "running" {
+		// goroutine menghapus filenya sendiri lewat finish()
+		j.Status = "canceled"
+		j.cancel()
+	} else {
+		os.Remove(j.path)
+	}
+	delete(js.jobs, id)
+	js.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "export job deleted"})
+}
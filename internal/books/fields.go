@@ -0,0 +1,119 @@
+package books
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidFields = errors.New("invalid fields")
+
+// bookField = satu field JSON Book yang bisa dipilih lewat ?fields=.
+type bookField struct {
+	name string // nama JSON (camelCase, sama dengan Detail)
+	col  string // ekspresi SQL di pgStore.List
+	ref  func(b *Book) any
+	val  func(b Book) any
+}
+
+var bookFieldList = []bookField{
+	{"id", "b.id", func(b *Book) any { return &b.ID }, func(b Book) any { return b.ID }},
+	{"ownerId", "COALESCE(b.owner_id, '')", func(b *Book) any { return &b.OwnerID }, func(b Book) any { return b.OwnerID }},
+	{"name", "b.name", func(b *Book) any { return &b.Name }, func(b Book) any { return b.Name }},
+	{"author", "COALESCE(b.author, '')", func(b *Book) any { return &b.Author }, func(b Book) any { return b.Author }},
+	{"publisher", "COALESCE(b.publisher, '')", func(b *Book) any { return &b.Publisher }, func(b Book) any { return b.Publisher }},
+	{"pageCount", "b.page_count", func(b *Book) any { return &b.PageCount }, func(b Book) any { return b.PageCount }},
+	{"readPage", "COALESCE(p.read_page, 0)", func(b *Book) any { return &b.ReadPage }, func(b Book) any { return b.ReadPage }},
+	{"reading", "COALESCE(p.reading, FALSE)", func(b *Book) any { return &b.Reading }, func(b Book) any { return b.Reading }},
+	{"finished", "COALESCE(p.finished, b.page_count = 0)", func(b *Book) any { return &b.Finished }, func(b Book) any { return b.Finished }},
+	{"insertedAt", "b.inserted_at", func(b *Book) any { return &b.InsertedAt }, func(b Book) any { return b.InsertedAt }},
+	{"updatedAt", "b.updated_at", func(b *Book) any { return &b.UpdatedAt }, func(b Book) any { return b.UpdatedAt }},
+}
+
+var bookFieldByName = func() map[string]bookField {
+	m := make(map[string]bookField, len(bookFieldList))
+	for _, f := range bookFieldList {
+		m[f.name] = f
+	}
+	return m
+}()
+
+// view=summary (default list); view=full = semua field
+var summaryFields = []string{"id", "name", "publisher"}
+
+// ParseFields membaca ?fields=a,b (prioritas) atau ?view=summary|full.
+// Hasil nil = semua field.
+func ParseFields(fields, view string) ([]string, error) {
+	if strings.TrimSpace(fields) != "" {
+		var out []string
+		seen := map[string]bool{}
+		for _, f := range strings.Split(fields, ",") {
+			f = strings.TrimSpace(f)
+			if _, ok := bookFieldByName[f]; !ok {
+				return nil, ErrInvalidFields
+			}
+			if !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+		}
+		return out, nil
+	}
+	switch view {
+	case "", "summary":
+		return summaryFields, nil
+	case "full":
+		return nil, nil
+	}
+	return nil, ErrInvalidFields
+}
+
+// sortDeps: field Book yang dibutuhkan utk menghitung tiap sort key (cursor).
+var sortDeps = map[string][]string{
+	"progress": {"readPage", "pageCount"},
+}
+
+// selectFields = fields + id + field yang dibutuhkan sort keys, urut sesuai
+// bookFieldList. nil fields = semua.
+func selectFields(fields []string, keys []SortKey) []bookField {
+	if fields == nil {
+		return bookFieldList
+	}
+	need := map[string]bool{"id": true}
+	for _, f := range fields {
+		need[f] = true
+	}
+	for _, k := range keys {
+		if deps, ok := sortDeps[k.Field]; ok {
+			for _, d := range deps {
+				need[d] = true
+			}
+		} else {
+			need[k.Field] = true
+		}
+	}
+	out := make([]bookField, 0, len(need))
+	for _, f := range bookFieldList {
+		if need[f.name] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// project merender Book hanya dengan field yang diminta (nil = semua).
+func project(b Book, fields []string) map[string]any {
+	out := make(map[string]any, len(bookFieldList)+1)
+	if fields == nil {
+		for _, f := range bookFieldList {
+			out[f.name] = f.val(b)
+		}
+	} else {
+		for _, name := range fields {
+			out[name] = bookFieldByName[name].val(b)
+		}
+	}
+	if b.Snippet != "" {
+		out["snippet"] = b.Snippet
+	}
+	return out
+}
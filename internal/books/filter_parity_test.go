@@ -0,0 +1,131 @@
+package books
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Filter GET /books harus memberi hasil yang sama di memStore dan pgStore
+// (strings.ToLower vs lower(), escape LIKE, COALESCE progress).
+// Bagian pg hanya jalan jika BOOKS_TEST_DB_URL menunjuk DB yang sudah dimigrasi.
+
+var parityBooks = []Book{
+	{Name: "Go Programming", Author: "Alan Donovan", Publisher: "Addison-Wesley", PageCount: 380, ReadPage: 380},
+	{Name: "go in action", Author: "William Kennedy", Publisher: "Manning", PageCount: 264, ReadPage: 100, Reading: true},
+	{Name: "Ärger im Paradies", Author: "Özil", Publisher: "ÜBER Verlag", PageCount: 200},
+	{Name: "100%_Pure", PageCount: 50, ReadPage: 25},
+	{Name: `Back\slash`, Author: `A\B`, PageCount: 10},
+	{Name: "Zero pages"},
+}
+
+var parityCases = []struct {
+	name  string
+	query string
+	want  []string // urut sort=name (COLLATE "C" = per byte)
+}{
+	{"name lower", "name=go", []string{"Go Programming", "go in action"}},
+	{"name upper", "name=GO", []string{"Go Programming", "go in action"}},
+	{"name non-ascii", "name=ärger", []string{"Ärger im Paradies"}},
+	{"name non-ascii upper", "name=ÄRGER", []string{"Ärger im Paradies"}},
+	{"author non-ascii", "author=%C3%B6zil", []string{"Ärger im Paradies"}},
+	{"publisher non-ascii", "publisher=%C3%BCber", []string{"Ärger im Paradies"}},
+	{"like percent", "name=%25", []string{"100%_Pure"}},
+	{"like underscore", "name=_", []string{"100%_Pure"}},
+	{"like backslash", `name=\`, []string{`Back\slash`}},
+	{"author backslash", `author=a\b`, []string{`Back\slash`}},
+	{"reading", "reading=1", []string{"go in action"}},
+	{"finished", "finished=1", []string{"Go Programming", "Zero pages"}},
+	{"not finished", "finished=0", []string{"100%_Pure", `Back\slash`, "go in action", "Ärger im Paradies"}},
+	{"pageCount range", "pageCountMin=200&pageCountMax=300", []string{"go in action", "Ärger im Paradies"}},
+	{"progress min", "progressMin=50", []string{"100%_Pure", "Go Programming"}},
+	{"readPage max", "readPageMax=0", []string{`Back\slash`, "Zero pages", "Ärger im Paradies"}},
+	{"combined", "name=o&finished=0", []string{"go in action"}},
+}
+
+func seedParity(t *testing.T, s Store, sc Scope) {
+	t.Helper()
+	for _, b := range parityBooks {
+		if _, err := s.Create(sc, &b); err != nil {
+			t.Fatalf("create %q: %v", b.Name, err)
+		}
+	}
+}
+
+func runParity(t *testing.T, s Store, sc Scope) map[string][]string {
+	t.Helper()
+	got := map[string][]string{}
+	for _, tc := range parityCases {
+		q, err := url.ParseQuery(tc.query + "&sort=name")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		f, err := ParseFilter(q)
+		if err != nil {
+			t.Fatalf("%s: ParseFilter: %v", tc.name, err)
+		}
+		f.Limit, f.Count = 100, CountNone
+		pg, err := s.List(sc, f)
+		if err != nil {
+			t.Fatalf("%s: List: %v", tc.name, err)
+		}
+		names := []string{}
+		for _, b := range pg.Items {
+			names = append(names, b.Name)
+		}
+		got[tc.name] = names
+	}
+	return got
+}
+
+func TestFilterMem(t *testing.T) {
+	sc := Scope{UserID: "u1"}
+	s := NewMemStore()
+	seedParity(t, s, sc)
+	got := runParity(t, s, sc)
+	for _, tc := range parityCases {
+		if !slices.Equal(got[tc.name], tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got[tc.name], tc.want)
+		}
+	}
+}
+
+func TestFilterParityPG(t *testing.T) {
+	dsn := os.Getenv("BOOKS_TEST_DB_URL")
+	if dsn == "" {
+		t.Skip("BOOKS_TEST_DB_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	uid := "parity-" + randomID()
+	if _, err := pool.Exec(ctx,
+		`INSERT INTO users (id, email, username, password) VALUES ($1, $1 || '@test.local', $1, '')`, uid,
+	); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(ctx, `DELETE FROM books WHERE owner_id = $1`, uid)
+		pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, uid)
+	})
+
+	sc := Scope{UserID: uid}
+	mem, pgs := NewMemStore(), NewPGStore(pool, false)
+	seedParity(t, mem, sc)
+	seedParity(t, pgs, sc)
+
+	want, got := runParity(t, mem, sc), runParity(t, pgs, sc)
+	for _, tc := range parityCases {
+		if !slices.Equal(got[tc.name], want[tc.name]) {
+			t.Errorf("%s: pg %q, mem %q", tc.name, got[tc.name], want[tc.name])
+		}
+	}
+}
@@ -2,18 +2,35 @@ package books
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/rbac"
 )
 
 import "log"
 
 type Handler struct {
 	Store Store
+	// RequireIfMatch: PUT/PATCH/DELETE tanpa If-Match ditolak 428.
+	RequireIfMatch bool
+
+	exports *exportJobs
 }
 
-func NewHandler(s Store) *Handler { return &Handler{Store: s} }
+func NewHandler(s Store) *Handler { return &Handler{Store: s, exports: newExportJobs()} }
+
+// scope: pemanggil dari JWT; izin books:all melihat semua buku tenant aktif
+// (org dari header X-Org-ID, lihat orgs.Handler.Active).
+func scope(r *http.Request) Scope {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	return Scope{UserID: uid, Admin: auth.HasPermission(r.Context(), rbac.BooksAll), OrgID: auth.OrgIDFromCtx(r.Context())}
+}
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -28,7 +45,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
 		return
 	}
-	id, err := h.Store.Create(&in)
+	id, err := h.Store.Create(scope(r), &in)
 	if err != nil {
 	switch err {
 	case ErrInvalidName:
@@ -47,7 +64,8 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GET /books?name=&reading=0|1&finished=0|1
+// GET /books?<filter, lihat ParseFilter>&limit=&offset=|after=|before=&count=exact|estimate|none
+//   &fields=id,name,...|view=summary|full
 func atoiDef(s string, def int) int {
   if n, err := strconv.Atoi(s); err == nil && n >= 0 { return n }
   return def
@@ -55,55 +73,107 @@ func atoiDef(s string, def int) int {
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
   q := r.URL.Query()
-  name := q.Get("name")
-
-  var readingPtr *bool
-  if v := q.Get("reading"); v == "0" || v == "1" { b := v == "1"; readingPtr = &b }
-  var finishedPtr *bool
-  if v := q.Get("finished"); v == "0" || v == "1" { b := v == "1"; finishedPtr = &b }
+  f, err := ParseFilter(q)
+  if err != nil {
+    writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid filter or sort"})
+    return
+  }
 
   limit := atoiDef(q.Get("limit"), 10)
   if limit > 100 { limit = 100 } // cap
   offset := atoiDef(q.Get("offset"), 0)
+  f.Limit, f.Offset, f.Count = limit, offset, q.Get("count")
+  if f.Fields, err = ParseFields(q.Get("fields"), q.Get("view")); err != nil {
+    writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid fields or view"})
+    return
+  }
 
-  items, total, _ := h.Store.List(Filter{
-    Name: name, Reading: readingPtr, Finished: finishedPtr,
-    Limit: limit, Offset: offset,
-  })
+  switch f.Count {
+  case "", CountExact, CountEstimate, CountNone:
+  default:
+    writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "count must be exact|estimate|none"})
+    return
+  }
+  for key, dst := range map[string]**Cursor{"after": &f.After, "before": &f.Before} {
+    v := q.Get(key)
+    if v == "" { continue }
+    if f.Q != "" {
+      // hasil q diurutkan per rank -> hanya offset
+      writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": key + " cursor cannot be combined with q; use offset"})
+      return
+    }
+    c, err := DecodeCursor(v, f.Sort)
+    if err != nil {
+      writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid " + key + " cursor"})
+      return
+    }
+    *dst = c
+  }
+  if f.After != nil && f.Before != nil {
+    writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "use either after or before"})
+    return
+  }
+
+  pg, err := h.Store.List(scope(r), f)
+  if err != nil {
+    log.Printf("[books.List] error: %v", err)
+    writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+    return
+  }
 
-  type light struct{ ID, Name, Publisher string }
-  out := make([]light, 0, len(items))
-  for _, b := range items {
-    out = append(out, light{ID: b.ID, Name: b.Name, Publisher: b.Publisher})
+  out := make([]map[string]any, 0, len(pg.Items))
+  for _, b := range pg.Items {
+    out = append(out, project(b, f.Fields))
+  }
+
+  meta := map[string]any{"limit": limit}
+  if f.After == nil && f.Before == nil { meta["offset"] = offset }
+  if pg.Total >= 0 { meta["total"] = pg.Total }
+  // q diurutkan per rank -> tetap offset, tanpa cursor
+  if f.Q == "" {
+    next, prev := pageLinks(w, r, pg, f.Sort)
+    if next != "" { meta["next"] = next }
+    if prev != "" { meta["prev"] = prev }
   }
 
   writeJSON(w, http.StatusOK, map[string]any{
     "status": "success",
     "data":   map[string]any{"books": out},
-    "meta":   map[string]any{"limit": limit, "offset": offset, "total": total},
+    "meta":   meta,
   })
 }
 
 // GET /books/{id}
 func (h *Handler) Detail(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/books/")
-	b, err := h.Store.Get(id)
+	b, err := h.Store.Get(scope(r), id)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "not found"})
 		return
 	}
+	tag := etag(b.Version)
+	w.Header().Set("ETag", tag)
+	if noneMatch(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"book": b}})
 }
 
 // PUT /books/{id}
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/books/")
+	ver, ok := h.precondition(w, r, id)
+	if !ok {
+		return
+	}
 	var in Book
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
 		return
 	}
-	if err := h.Store.Update(id, in); err != nil {
+	b, err := h.Store.Update(scope(r), id, in, ver)
+	if err != nil {
 		switch err {
 		case ErrInvalidName:
 			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "name is required"})
@@ -111,18 +181,103 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "readPage must be <= pageCount"})
 		case ErrNotFound:
 			writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "id not found"})
+		case ErrVersionMismatch:
+			writeJSON(w, http.StatusPreconditionFailed, map[string]any{"status": "fail", "message": "version mismatch"})
+		case ErrForbidden:
+			writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "only the owner can modify this book"})
 		default:
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
 		}
 		return
 	}
+	w.Header().Set("ETag", etag(b.Version))
 	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "updated"})
 }
 
+// PATCH /books/{id}
+// Content-Type application/merge-patch+json (RFC 7396, default) atau
+// application/json-patch+json (RFC 6902).
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/books/")
+	ver, ok := h.precondition(w, r, id)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid body"})
+		return
+	}
+
+	var patch BookPatch
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "", "application/json", "application/merge-patch+json":
+		patch, err = ParseMergePatch(body)
+	case "application/json-patch+json":
+		cur, gerr := h.Store.Get(scope(r), id)
+		if gerr != nil {
+			writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "id not found"})
+			return
+		}
+		patch, err = ParseJSONPatch(body, cur)
+		if ver == 0 {
+			ver = cur.Version // op "test" dicek thd versi ini
+		}
+	default:
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]any{"status": "fail", "message": "unsupported content type"})
+		return
+	}
+	if err != nil {
+		writePatchError(w, err)
+		return
+	}
+
+	b, err := h.Store.Patch(scope(r), id, patch, ver)
+	if err != nil {
+		writePatchError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(b.Version))
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"book": b}})
+}
+
+func writePatchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidName):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "name is required"})
+	case errors.Is(err, ErrReadPageTooBig):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "readPage must be <= pageCount"})
+	case errors.Is(err, ErrInvalidPatch):
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "id not found"})
+	case errors.Is(err, ErrVersionMismatch):
+		writeJSON(w, http.StatusPreconditionFailed, map[string]any{"status": "fail", "message": "version mismatch"})
+	case errors.Is(err, ErrForbidden):
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "only the owner can modify this book"})
+	default:
+		log.Printf("[books.Patch] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
+
 // DELETE /books/{id}
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/books/")
-	if err := h.Store.Delete(id); err != nil {
+	ver, ok := h.precondition(w, r, id)
+	if !ok {
+		return
+	}
+	if err := h.Store.Delete(scope(r), id, ver); err != nil {
+		switch err {
+		case ErrVersionMismatch:
+			writeJSON(w, http.StatusPreconditionFailed, map[string]any{"status": "fail", "message": "version mismatch"})
+			return
+		case ErrForbidden:
+			writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "only the owner can delete this book"})
+			return
+		}
 		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "id not found"})
 		return
 	}
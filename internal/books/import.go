@@ -0,0 +1,361 @@
+package books
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 10000
+)
+
+var errTooManyRows = fmt.Errorf("too many rows (max %d)", maxImportRows)
+
+// importRow = satu baris input; Line 1-based (CSV: baris fisik awal record,
+// termasuk header).
+type importRow struct {
+	Line int
+	Book Book
+	Err  error
+}
+
+type importInput struct {
+	Name      string `json:"name"`
+	Author    string `json:"author"`
+	Publisher string `json:"publisher"`
+	PageCount int    `json:"pageCount"`
+	ReadPage  int    `json:"readPage"`
+	Reading   bool   `json:"reading"`
+}
+
+func (in importInput) book() (Book, error) {
+	if in.PageCount < 0 || in.ReadPage < 0 {
+		return Book{}, errors.New("pageCount/readPage must be >= 0")
+	}
+	return Book{Name: in.Name, Author: in.Author, Publisher: in.Publisher,
+		PageCount: in.PageCount, ReadPage: in.ReadPage, Reading: in.Reading}, nil
+}
+
+// importFormat: ?format= menang atas Content-Type.
+func importFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "text/csv":
+		return "csv"
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return "ndjson"
+	case "application/json":
+		return "json"
+	}
+	return ""
+}
+
+// parseHeaderMap membaca ?map=Judul:name,Penulis:author (header CSV -> field).
+func parseHeaderMap(s string) (map[string]string, error) {
+	out := map[string]string{}
+	if s == "" {
+		return out, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(pair, ":")
+		if !ok || canonicalImportField(to) == "" {
+			return nil, fmt.Errorf("invalid map entry %q", pair)
+		}
+		out[strings.ToLower(strings.TrimSpace(from))] = canonicalImportField(to)
+	}
+	return out, nil
+}
+
+// canonicalImportField: "page_count", "Page Count", "pageCount" -> "pageCount".
+func canonicalImportField(s string) string {
+	k := strings.NewReplacer("_", "", " ", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(s)))
+	switch k {
+	case "name", "author", "publisher", "reading":
+		return k
+	case "pagecount":
+		return "pageCount"
+	case "readpage":
+		return "readPage"
+	}
+	return ""
+}
+
+func parseCSV(r io.Reader, headerMap map[string]string) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	cols := make([]string, len(header))
+	hasName := false
+	for i, h := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if to, ok := headerMap[key]; ok {
+			cols[i] = to
+		} else {
+			cols[i] = canonicalImportField(key)
+		}
+		hasName = hasName || cols[i] == "name"
+	}
+	if !hasName {
+		return nil, errors.New("no column mapped to name")
+	}
+
+	var rows []importRow
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if len(rows) >= maxImportRows {
+			return nil, errTooManyRows
+		}
+		var row importRow
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return nil, err
+			}
+			row.Line, row.Err = pe.StartLine, err
+			rows = append(rows, row)
+			continue
+		}
+		// baris fisik awal record (field berkutip bisa memuat newline)
+		row.Line, _ = cr.FieldPos(0)
+		var in importInput
+		for i, v := range rec {
+			if i >= len(cols) || cols[i] == "" {
+				continue
+			}
+			v = strings.TrimSpace(v)
+			switch cols[i] {
+			case "name":
+				in.Name = v
+			case "author":
+				in.Author = v
+			case "publisher":
+				in.Publisher = v
+			case "pageCount", "readPage":
+				n := 0
+				if v != "" {
+					if n, err = strconv.Atoi(v); err != nil {
+						row.Err = fmt.Errorf("%s: not an integer", cols[i])
+					}
+				}
+				if cols[i] == "pageCount" {
+					in.PageCount = n
+				} else {
+					in.ReadPage = n
+				}
+			case "reading":
+				switch strings.ToLower(v) {
+				case "", "0", "false", "no", "n":
+				case "1", "true", "yes", "y":
+					in.Reading = true
+				default:
+					row.Err = errors.New("reading: not a boolean")
+				}
+			}
+		}
+		if row.Err == nil {
+			row.Book, row.Err = in.book()
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseJSONArray(r io.Reader) ([]importRow, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("expected JSON array")
+	}
+	var rows []importRow
+	for line := 1; dec.More(); line++ {
+		if len(rows) >= maxImportRows {
+			return nil, errTooManyRows
+		}
+		var in importInput
+		row := importRow{Line: line}
+		if err := dec.Decode(&in); err != nil {
+			// type error = baris ini saja; syntax error = seluruh dokumen
+			var te *json.UnmarshalTypeError
+			if !errors.As(err, &te) {
+				return nil, err
+			}
+			row.Err = fmt.Errorf("%s: wrong type", te.Field)
+		} else {
+			row.Book, row.Err = in.book()
+		}
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func parseNDJSON(r io.Reader) ([]importRow, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	var rows []importRow
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			return nil, errTooManyRows
+		}
+		var in importInput
+		row := importRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &in); err != nil {
+			row.Err = errors.New("invalid json")
+		} else {
+			row.Book, row.Err = in.book()
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
+
+type importReport struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"` // valid | imported | invalid | failed | skipped
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// POST /books/import?format=csv|json|ndjson&mode=atomic|best-effort&dryRun=1&map=Header:field
+//
+// Tiap baris divalidasi dengan aturan Create. atomic (default): satu baris
+// gagal -> tidak ada yang disimpan (422). best-effort: baris valid tetap disimpan.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = "atomic"
+	}
+	if mode != "atomic" && mode != "best-effort" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "mode must be atomic|best-effort"})
+		return
+	}
+	dryRun := q.Get("dryRun") == "1" || q.Get("dryRun") == "true"
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	var rows []importRow
+	var err error
+	switch importFormat(r) {
+	case "csv":
+		var hm map[string]string
+		if hm, err = parseHeaderMap(q.Get("map")); err == nil {
+			rows, err = parseCSV(body, hm)
+		}
+	case "json":
+		rows, err = parseJSONArray(body)
+	case "ndjson":
+		rows, err = parseNDJSON(body)
+	default:
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]any{"status": "fail", "message": "format must be csv|json|ndjson"})
+		return
+	}
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"status": "fail", "message": fmt.Sprintf("body too large (max %d bytes)", mbe.Limit)})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	report := make([]importReport, len(rows))
+	var valid []int // index ke rows
+	for i := range rows {
+		report[i].Line = rows[i].Line
+		if rows[i].Err == nil {
+			rows[i].Err = validate(&rows[i].Book)
+		}
+		if rows[i].Err != nil {
+			report[i].Status, report[i].Error = "invalid", rows[i].Err.Error()
+			continue
+		}
+		report[i].Status = "valid"
+		valid = append(valid, i)
+	}
+	invalid := len(rows) - len(valid)
+
+	write := func(code int, imported int) {
+		status := "success"
+		if code >= 400 {
+			status = "fail"
+		}
+		writeJSON(w, code, map[string]any{
+			"status": status,
+			"data": map[string]any{
+				"dryRun": dryRun, "mode": mode,
+				"total": len(rows), "imported": imported, "invalid": invalid,
+				"rows": report,
+			},
+		})
+	}
+
+	if mode == "atomic" && invalid > 0 {
+		for _, i := range valid {
+			report[i].Status = "skipped"
+		}
+		write(http.StatusUnprocessableEntity, 0)
+		return
+	}
+	if dryRun || len(valid) == 0 {
+		write(http.StatusOK, 0)
+		return
+	}
+
+	sc := scope(r)
+	batch := make([]Book, len(valid))
+	for j, i := range valid {
+		batch[j] = rows[i].Book
+	}
+	ids, err := h.Store.Import(sc, batch)
+	if err != nil && mode == "atomic" {
+		log.Printf("[books.Import] batch error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	imported := 0
+	if err == nil {
+		for j, i := range valid {
+			report[i].Status, report[i].ID = "imported", ids[j]
+		}
+		imported = len(valid)
+	} else {
+		// best-effort: batch gagal di DB -> ulangi per baris utk mengisolasi error
+		log.Printf("[books.Import] batch error, falling back to single inserts: %v", err)
+		for _, i := range valid {
+			b := rows[i].Book
+			id, err := h.Store.Create(sc, &b)
+			if err != nil {
+				report[i].Status, report[i].Error = "failed", err.Error()
+				continue
+			}
+			report[i].Status, report[i].ID = "imported", id
+			imported++
+		}
+	}
+	write(http.StatusOK, imported)
+}
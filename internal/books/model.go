@@ -2,8 +2,12 @@ package books
 
 import "time"
 
+// Book = metadata buku + state baca milik user yang sedang mengakses
+// (ReadPage/Reading/Finished disimpan per user, lihat Scope).
 type Book struct {
 	ID        string    `json:"id"`
+	OwnerID   string    `json:"ownerId,omitempty"`
+	OrgID     string    `json:"-"` // tenant, selalu sama dgn Scope.OrgID pemanggil
 	Name      string    `json:"name"`
 	Author    string    `json:"author"`
 	Publisher string    `json:"publisher"`
@@ -13,4 +17,26 @@ type Book struct {
 	Finished  bool      `json:"finished"`
 	InsertedAt time.Time `json:"insertedAt"`
 	UpdatedAt  time.Time `json:"updatedAt"`
+	Version    int       `json:"-"` // dipakai sbg ETag
+	Snippet    string    `json:"snippet,omitempty"` // hanya utk hasil pencarian q
+}
+
+// Session = satu sesi baca. EndedAt nil berarti sesi masih berjalan.
+type Session struct {
+	ID        string     `json:"id"`
+	BookID    string     `json:"bookId"`
+	UserID    string     `json:"userId,omitempty"`
+	StartPage int        `json:"startPage"`
+	EndPage   int        `json:"endPage"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+// Scope = siapa yang memanggil store. Non-admin hanya melihat buku miliknya
+// atau yang sedang ia lacak (punya progress/sesi baca). OrgID = tenant aktif
+// ("" = ruang pribadi); buku tenant lain tidak pernah terlihat, admin sekalipun.
+type Scope struct {
+	UserID string
+	Admin  bool
+	OrgID  string
 }
@@ -0,0 +1,184 @@
+package books
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch document")
+
+// BookPatch berisi perubahan parsial; field nil = tidak diubah.
+type BookPatch struct {
+	Name      *string
+	Author    *string
+	Publisher *string
+	PageCount *int
+}
+
+// field read-only yang boleh ada di dokumen patch tapi diabaikan
+var readOnlyFields = map[string]bool{"id": true, "ownerId": true, "finished": true, "insertedAt": true, "updatedAt": true}
+
+// ParseMergePatch decode dokumen RFC 7396 (JSON Merge Patch).
+// null berarti "hapus": author/publisher jadi "", pageCount jadi 0.
+func ParseMergePatch(data []byte) (BookPatch, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return BookPatch{}, ErrInvalidPatch
+	}
+	var p BookPatch
+	for k, raw := range doc {
+		if err := p.set(k, raw); err != nil {
+			return BookPatch{}, err
+		}
+	}
+	return p, nil
+}
+
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ParseJSONPatch decode dokumen RFC 6902 (JSON Patch) terhadap buku saat ini.
+// Hanya path top-level yang didukung; op move/copy ditolak.
+// Op dijalankan berurutan: "test" dicek thd hasil op sebelumnya (RFC 6902 §5).
+func ParseJSONPatch(data []byte, cur *Book) (BookPatch, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return BookPatch{}, ErrInvalidPatch
+	}
+	doc := *cur
+	var curFields map[string]json.RawMessage
+	refresh := func() {
+		raw, _ := json.Marshal(doc)
+		curFields = nil
+		_ = json.Unmarshal(raw, &curFields)
+	}
+	refresh()
+
+	var p BookPatch
+	for _, op := range ops {
+		key := strings.TrimPrefix(op.Path, "/")
+		if key == op.Path || key == "" || strings.Contains(key, "/") {
+			return BookPatch{}, fmt.Errorf("%w: unsupported path %q", ErrInvalidPatch, op.Path)
+		}
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return BookPatch{}, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+			}
+			if err := p.set(key, op.Value); err != nil {
+				return BookPatch{}, err
+			}
+			p.applyTo(&doc)
+			refresh()
+		case "remove":
+			if err := p.set(key, json.RawMessage("null")); err != nil {
+				return BookPatch{}, err
+			}
+			p.applyTo(&doc)
+			refresh()
+		case "test":
+			have, ok := curFields[key]
+			if !ok || !jsonEqual(have, op.Value) {
+				return BookPatch{}, fmt.Errorf("%w: test failed for %q", ErrInvalidPatch, op.Path)
+			}
+		default:
+			return BookPatch{}, fmt.Errorf("%w: unsupported op %q", ErrInvalidPatch, op.Op)
+		}
+	}
+	return p, nil
+}
+
+func (p *BookPatch) set(key string, raw json.RawMessage) error {
+	isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+	bad := func() error { return fmt.Errorf("%w: bad value for %q", ErrInvalidPatch, key) }
+	switch key {
+	case "name":
+		if isNull {
+			return ErrInvalidName
+		}
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return bad()
+		}
+		p.Name = &s
+	case "author", "publisher":
+		var s string
+		if !isNull && json.Unmarshal(raw, &s) != nil {
+			return bad()
+		}
+		if key == "author" {
+			p.Author = &s
+		} else {
+			p.Publisher = &s
+		}
+	case "pageCount":
+		var n int
+		if !isNull && (json.Unmarshal(raw, &n) != nil || n < 0) {
+			return bad()
+		}
+		p.PageCount = &n
+	case "readPage", "reading":
+		// state baca diturunkan dari sesi baca
+		return fmt.Errorf("%w: %q is derived from reading sessions, use POST /books/{id}/sessions", ErrInvalidPatch, key)
+	default:
+		if !readOnlyFields[key] {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidPatch, key)
+		}
+	}
+	return nil
+}
+
+// applyTo menggabungkan patch ke b lalu menghitung ulang finished.
+// readPage tidak diubah: pageCount < readPage ditolak (validate / store).
+func (p BookPatch) applyTo(b *Book) {
+	if p.Name != nil {
+		b.Name = *p.Name
+	}
+	if p.Author != nil {
+		b.Author = *p.Author
+	}
+	if p.Publisher != nil {
+		b.Publisher = *p.Publisher
+	}
+	if p.PageCount != nil {
+		b.PageCount = *p.PageCount
+	}
+	b.Finished = b.PageCount == b.ReadPage
+}
+
+func (p BookPatch) touchesMeta() bool {
+	return p.Name != nil || p.Author != nil || p.Publisher != nil || p.PageCount != nil
+}
+
+// fullPatch mengubah body PUT (representasi penuh) jadi patch semua field.
+// readPage/reading diabaikan: state baca hanya berubah lewat sesi baca.
+func fullPatch(b Book) BookPatch {
+	return BookPatch{Name: &b.Name, Author: &b.Author, Publisher: &b.Publisher, PageCount: &b.PageCount}
+}
+
+// validate memakai aturan yang sama dengan Create/Update.
+func validate(b *Book) error {
+	if strings.TrimSpace(b.Name) == "" {
+		return ErrInvalidName
+	}
+	if b.ReadPage > b.PageCount {
+		return ErrReadPageTooBig
+	}
+	return nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	xb, _ := json.Marshal(x)
+	yb, _ := json.Marshal(y)
+	return bytes.Equal(xb, yb)
+}
@@ -0,0 +1,402 @@
+package books
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query builder bersama pgStore & memStore: tiap field/predikat punya
+// ekspresi SQL dan padanan Go-nya, supaya kedua store memberi hasil identik.
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+type fieldKind int
+
+const (
+	kindStr fieldKind = iota
+	kindInt
+	kindFloat
+	kindTime
+)
+
+type fieldDef struct {
+	col  string // ekspresi SQL (alias b = books, p = book_progress)
+	kind fieldKind
+	get  func(b Book) any
+}
+
+// progress baca (persen). float8 di SQL supaya pembulatannya sama dgn Go.
+const progressSQL = `(CASE WHEN b.page_count = 0 THEN 0 ELSE COALESCE(p.read_page, 0)::float8 * 100 / b.page_count END)`
+
+func progressOf(b Book) float64 {
+	if b.PageCount == 0 {
+		return 0
+	}
+	return float64(b.ReadPage) * 100 / float64(b.PageCount)
+}
+
+// sortFields = whitelist field utk sort. String dibandingkan per byte
+// (COLLATE "C") agar urutannya sama dengan perbandingan string Go.
+var sortFields = map[string]fieldDef{
+	"id":         {`b.id COLLATE "C"`, kindStr, func(b Book) any { return b.ID }},
+	"name":       {`b.name COLLATE "C"`, kindStr, func(b Book) any { return b.Name }},
+	"author":     {`COALESCE(b.author, '') COLLATE "C"`, kindStr, func(b Book) any { return b.Author }},
+	"publisher":  {`COALESCE(b.publisher, '') COLLATE "C"`, kindStr, func(b Book) any { return b.Publisher }},
+	"pageCount":  {`b.page_count`, kindInt, func(b Book) any { return b.PageCount }},
+	"readPage":   {`COALESCE(p.read_page, 0)`, kindInt, func(b Book) any { return b.ReadPage }},
+	"progress":   {progressSQL, kindFloat, func(b Book) any { return progressOf(b) }},
+	"insertedAt": {`b.inserted_at`, kindTime, func(b Book) any { return b.InsertedAt }},
+	"updatedAt":  {`b.updated_at`, kindTime, func(b Book) any { return b.UpdatedAt }},
+}
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+var defaultSort = []SortKey{{"insertedAt", true}, {"id", true}}
+
+// ParseSort membaca "sort=-updatedAt,name". id selalu ditambahkan sebagai
+// tie-breaker supaya urutan total (wajib utk keyset pagination).
+func ParseSort(s string) ([]SortKey, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaultSort, nil
+	}
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		k := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := sortFields[k.Field]; !ok || seen[k.Field] {
+			return nil, ErrInvalidFilter
+		}
+		seen[k.Field] = true
+		keys = append(keys, k)
+	}
+	if !seen["id"] {
+		keys = append(keys, SortKey{"id", keys[len(keys)-1].Desc})
+	}
+	return keys, nil
+}
+
+func (f Filter) sortKeys() []SortKey {
+	if len(f.Sort) == 0 {
+		return defaultSort
+	}
+	return f.Sort
+}
+
+func sortSig(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func compareValues(a, b any) int {
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case int:
+		y := b.(int)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case float64:
+		y := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// compareBooks: urutan a vs b menurut keys (negatif = a lebih dulu).
+func compareBooks(a, b Book, keys []SortKey) int {
+	for _, k := range keys {
+		get := sortFields[k.Field].get
+		c := compareValues(get(a), get(b))
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func orderBySQL(keys []SortKey, reverse bool) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		dir := k.Desc != reverse
+		parts[i] = sortFields[k.Field].col
+		if dir {
+			parts[i] += " DESC"
+		} else {
+			parts[i] += " ASC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sqlArgs mengumpulkan argumen query dan memberi placeholder $n.
+type sqlArgs []any
+
+func (a *sqlArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// keysetSQL: kondisi "baris sesudah cursor" (atau sebelum jika before) utk
+// keys dengan arah campuran: (k1 op v1) OR (k1 = v1 AND k2 op v2) OR ...
+func keysetSQL(keys []SortKey, c *Cursor, before bool, args *sqlArgs) string {
+	ors := make([]string, 0, len(keys))
+	var eqs []string
+	for i, k := range keys {
+		col := sortFields[k.Field].col
+		ph := args.add(c.values[i])
+		op := ">"
+		if k.Desc != before {
+			op = "<"
+		}
+		ors = append(ors, "("+strings.Join(append(eqs[:len(eqs):len(eqs)], col+" "+op+" "+ph), " AND ")+")")
+		eqs = append(eqs, col+" = "+ph)
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
+
+// --- predikat filter ---
+
+type predicate struct {
+	sql   func(args *sqlArgs) string
+	match func(b Book) bool
+}
+
+type IntRange struct{ Min, Max *int }
+type FloatRange struct{ Min, Max *float64 }
+type TimeRange struct{ From, To *time.Time } // From inklusif, To eksklusif
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func containsPred(col string, get func(Book) string, needle string) predicate {
+	needle = strings.ToLower(strings.TrimSpace(needle))
+	return predicate{
+		sql: func(a *sqlArgs) string {
+			return "lower(" + col + ") LIKE " + a.add("%"+likeEscape(needle)+"%")
+		},
+		match: func(b Book) bool { return strings.Contains(strings.ToLower(get(b)), needle) },
+	}
+}
+
+func cmpPred(field, op string, v any) predicate {
+	fd := sortFields[field]
+	return predicate{
+		sql: func(a *sqlArgs) string { return fd.col + " " + op + " " + a.add(v) },
+		match: func(b Book) bool {
+			c := compareValues(fd.get(b), v)
+			switch op {
+			case ">=":
+				return c >= 0
+			case "<=":
+				return c <= 0
+			case "<":
+				return c < 0
+			}
+			return c == 0
+		},
+	}
+}
+
+// predicates menerjemahkan Filter (selain Q) ke daftar predikat.
+func (f Filter) predicates() []predicate {
+	var ps []predicate
+	if strings.TrimSpace(f.Name) != "" {
+		ps = append(ps, containsPred("b.name", func(b Book) string { return b.Name }, f.Name))
+	}
+	if strings.TrimSpace(f.Author) != "" {
+		ps = append(ps, containsPred("COALESCE(b.author, '')", func(b Book) string { return b.Author }, f.Author))
+	}
+	if strings.TrimSpace(f.Publisher) != "" {
+		ps = append(ps, containsPred("COALESCE(b.publisher, '')", func(b Book) string { return b.Publisher }, f.Publisher))
+	}
+	if f.Reading != nil {
+		v := *f.Reading
+		ps = append(ps, predicate{
+			sql:   func(a *sqlArgs) string { return "COALESCE(p.reading, FALSE) = " + a.add(v) },
+			match: func(b Book) bool { return b.Reading == v },
+		})
+	}
+	if f.Finished != nil {
+		v := *f.Finished
+		ps = append(ps, predicate{
+			sql:   func(a *sqlArgs) string { return "COALESCE(p.finished, b.page_count = 0) = " + a.add(v) },
+			match: func(b Book) bool { return b.Finished == v },
+		})
+	}
+	for _, x := range []struct {
+		field string
+		r     IntRange
+	}{{"pageCount", f.PageCount}, {"readPage", f.ReadPage}} {
+		if x.r.Min != nil {
+			ps = append(ps, cmpPred(x.field, ">=", *x.r.Min))
+		}
+		if x.r.Max != nil {
+			ps = append(ps, cmpPred(x.field, "<=", *x.r.Max))
+		}
+	}
+	if f.Progress.Min != nil {
+		ps = append(ps, cmpPred("progress", ">=", *f.Progress.Min))
+	}
+	if f.Progress.Max != nil {
+		ps = append(ps, cmpPred("progress", "<=", *f.Progress.Max))
+	}
+	for _, x := range []struct {
+		field string
+		r     TimeRange
+	}{{"insertedAt", f.InsertedAt}, {"updatedAt", f.UpdatedAt}} {
+		if x.r.From != nil {
+			ps = append(ps, cmpPred(x.field, ">=", *x.r.From))
+		}
+		if x.r.To != nil {
+			ps = append(ps, cmpPred(x.field, "<", *x.r.To))
+		}
+	}
+	if len(f.IDs) > 0 {
+		set := make(map[string]bool, len(f.IDs))
+		for _, id := range f.IDs {
+			set[id] = true
+		}
+		ids := f.IDs
+		ps = append(ps, predicate{
+			sql:   func(a *sqlArgs) string { return "b.id = ANY(" + a.add(ids) + ")" },
+			match: func(b Book) bool { return set[b.ID] },
+		})
+	}
+	return ps
+}
+
+func matchAll(ps []predicate, b Book) bool {
+	for _, p := range ps {
+		if !p.match(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFilter membaca query string GET /books (juga dipakai endpoint lain
+// yang menerima filter yang sama). Limit/Offset/cursor diurus pemanggil.
+//
+//	q, name, author, publisher, reading=0|1, finished=0|1,
+//	pageCountMin/Max, readPageMin/Max, progressMin/Max (persen),
+//	insertedFrom/To, updatedFrom/To (RFC 3339), ids=a,b,c,
+//	sort=-updatedAt,name
+func ParseFilter(q url.Values) (Filter, error) {
+	f := Filter{
+		Q: q.Get("q"), Name: q.Get("name"),
+		Author: q.Get("author"), Publisher: q.Get("publisher"),
+	}
+	if v := q.Get("reading"); v == "0" || v == "1" {
+		b := v == "1"
+		f.Reading = &b
+	}
+	if v := q.Get("finished"); v == "0" || v == "1" {
+		b := v == "1"
+		f.Finished = &b
+	}
+
+	intParam := func(key string) (*int, error) {
+		v := q.Get(key)
+		if v == "" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, ErrInvalidFilter
+		}
+		return &n, nil
+	}
+	floatParam := func(key string) (*float64, error) {
+		v := q.Get(key)
+		if v == "" {
+			return nil, nil
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 || n > 100 {
+			return nil, ErrInvalidFilter
+		}
+		return &n, nil
+	}
+	timeParam := func(key string) (*time.Time, error) {
+		v := q.Get(key)
+		if v == "" {
+			return nil, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, ErrInvalidFilter
+		}
+		return &t, nil
+	}
+
+	var errs []error
+	collectInt := func(dst **int, key string) {
+		v, err := intParam(key)
+		*dst, errs = v, append(errs, err)
+	}
+	collectTime := func(dst **time.Time, key string) {
+		v, err := timeParam(key)
+		*dst, errs = v, append(errs, err)
+	}
+	collectInt(&f.PageCount.Min, "pageCountMin")
+	collectInt(&f.PageCount.Max, "pageCountMax")
+	collectInt(&f.ReadPage.Min, "readPageMin")
+	collectInt(&f.ReadPage.Max, "readPageMax")
+	var err error
+	f.Progress.Min, err = floatParam("progressMin")
+	errs = append(errs, err)
+	f.Progress.Max, err = floatParam("progressMax")
+	errs = append(errs, err)
+	collectTime(&f.InsertedAt.From, "insertedFrom")
+	collectTime(&f.InsertedAt.To, "insertedTo")
+	collectTime(&f.UpdatedAt.From, "updatedFrom")
+	collectTime(&f.UpdatedAt.To, "updatedTo")
+	if err := errors.Join(errs...); err != nil {
+		return Filter{}, ErrInvalidFilter
+	}
+
+	if v := q.Get("ids"); v != "" {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				f.IDs = append(f.IDs, id)
+			}
+		}
+		if len(f.IDs) > 100 {
+			return Filter{}, ErrInvalidFilter
+		}
+	}
+
+	f.Sort, err = ParseSort(q.Get("sort"))
+	if err != nil {
+		return Filter{}, err
+	}
+	return f, nil
+}
@@ -0,0 +1,286 @@
+package books
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Parser query pencarian (param q), dipakai kedua store supaya hasilnya sama:
+//
+//	kata           -> harus ada
+//	kata*          -> prefix
+//	"dua kata"     -> frasa (berurutan)
+//	-kata          -> tidak boleh ada
+//
+// Tokenisasi meniru to_tsvector('simple', ...): lowercase, pisah di non huruf/angka.
+
+type searchTerm struct {
+	words  []string // >1 = frasa
+	prefix bool     // kata terakhir dicocokkan sbg prefix
+	negate bool
+}
+
+type searchQuery []searchTerm
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func parseSearch(q string) searchQuery {
+	var out searchQuery
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+		var t searchTerm
+		if q[0] == '-' {
+			t.negate = true
+			q = q[1:]
+		}
+		var raw string
+		if strings.HasPrefix(q, `"`) {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				raw, q = q[1:], ""
+			} else {
+				raw, q = q[1:end+1], q[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			raw, q = q[:end], q[end:]
+		}
+		t.prefix = strings.HasSuffix(raw, "*")
+		t.words = tokenize(raw)
+		if len(t.words) > 0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// tsquery menghasilkan input utk to_tsquery('simple', ...).
+// Token hanya berisi huruf/angka sehingga aman di dalam kutip.
+func (sq searchQuery) tsquery() string {
+	parts := make([]string, 0, len(sq))
+	for _, t := range sq {
+		ws := make([]string, len(t.words))
+		for i, w := range t.words {
+			ws[i] = "'" + w + "'"
+		}
+		if t.prefix {
+			ws[len(ws)-1] += ":*"
+		}
+		p := strings.Join(ws, " <-> ")
+		if len(ws) > 1 {
+			p = "(" + p + ")"
+		}
+		if t.negate {
+			p = "!" + p
+		}
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, " & ")
+}
+
+// bobot field meniru ts_rank default: A=1.0 (name), B=0.4 (author), C=0.2 (publisher)
+var fieldWeights = [3]float64{1.0, 0.4, 0.2}
+
+func bookFields(b Book) [3]string { return [3]string{b.Name, b.Author, b.Publisher} }
+
+func wordMatch(tok, w string, prefix bool) bool {
+	if prefix {
+		return strings.HasPrefix(tok, w)
+	}
+	return tok == w
+}
+
+// occurrences menghitung kemunculan term (frasa/prefix) di deretan token.
+func (t searchTerm) occurrences(toks []string) int {
+	n := 0
+	for i := 0; i+len(t.words) <= len(toks); i++ {
+		ok := true
+		for j, w := range t.words {
+			if !wordMatch(toks[i+j], w, t.prefix && j == len(t.words)-1) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// score: 0,false jika buku tidak cocok.
+func (sq searchQuery) score(b Book) (float64, bool) {
+	fields := bookFields(b)
+	var toks [3][]string
+	for i, f := range fields {
+		toks[i] = tokenize(f)
+	}
+	var total float64
+	for _, t := range sq {
+		var s float64
+		for i := range toks {
+			s += fieldWeights[i] * float64(t.occurrences(toks[i]))
+		}
+		if t.negate {
+			if s > 0 {
+				return 0, false
+			}
+			continue
+		}
+		if s == 0 {
+			return 0, false
+		}
+		total += s
+	}
+	return total, true
+}
+
+// Penanda highlight sementara; snippet di-escape dulu (HTML) baru penanda
+// diganti <b></b>, supaya isi field tidak pernah tampil sbg markup.
+const (
+	hlStart = "\x02"
+	hlStop  = "\x03"
+)
+
+// headlineOpts: opsi ts_headline dgn penanda yang sama dgn memStore
+// (konstanta, disisipkan sbg literal SQL).
+const headlineOpts = "StartSel=" + hlStart + ", StopSel=" + hlStop + ", HighlightAll=true"
+
+var hlMarkup = strings.NewReplacer(hlStart, "<b>", hlStop, "</b>")
+
+// snippetHTML escape teks lalu mengganti penanda jadi <b></b>.
+func snippetHTML(marked string) string {
+	return hlMarkup.Replace(html.EscapeString(marked))
+}
+
+// highlight membungkus token yang cocok dgn <b></b>, seperti ts_headline.
+func (sq searchQuery) highlight(b Book) string {
+	var parts []string
+	for _, f := range bookFields(b) {
+		if f != "" {
+			parts = append(parts, f)
+		}
+	}
+	text := strings.Join(parts, " - ")
+
+	hit := func(tok string) bool {
+		for _, t := range sq {
+			if t.negate {
+				continue
+			}
+			for j, w := range t.words {
+				if wordMatch(tok, w, t.prefix && j == len(t.words)-1) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var sb strings.Builder
+	rs := []rune(text)
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for i := 0; i < len(rs); {
+		if !isWord(rs[i]) {
+			sb.WriteRune(rs[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(rs) && isWord(rs[j]) {
+			j++
+		}
+		word := string(rs[i:j])
+		if hit(strings.ToLower(word)) {
+			sb.WriteString(hlStart + word + hlStop)
+		} else {
+			sb.WriteString(word)
+		}
+		i = j
+	}
+	return snippetHTML(sb.String())
+}
+
+// invertedIndex: token -> id buku, utk mempersempit kandidat di memStore.
+type invertedIndex map[string]map[string]struct{}
+
+func (ix invertedIndex) add(b Book) {
+	for _, f := range bookFields(b) {
+		for _, tok := range tokenize(f) {
+			if ix[tok] == nil {
+				ix[tok] = map[string]struct{}{}
+			}
+			ix[tok][b.ID] = struct{}{}
+		}
+	}
+}
+
+func (ix invertedIndex) remove(b Book) {
+	for _, f := range bookFields(b) {
+		for _, tok := range tokenize(f) {
+			delete(ix[tok], b.ID)
+			if len(ix[tok]) == 0 {
+				delete(ix, tok)
+			}
+		}
+	}
+}
+
+// candidates: irisan id yang memuat kata pertama tiap term positif.
+// nil = tidak ada term positif (semua buku jadi kandidat).
+func (ix invertedIndex) candidates(sq searchQuery) map[string]struct{} {
+	var out map[string]struct{}
+	for _, t := range sq {
+		if t.negate {
+			continue
+		}
+		ids := map[string]struct{}{}
+		w, prefix := t.words[0], t.prefix && len(t.words) == 1
+		if prefix {
+			for tok, set := range ix {
+				if strings.HasPrefix(tok, w) {
+					for id := range set {
+						ids[id] = struct{}{}
+					}
+				}
+			}
+		} else {
+			for id := range ix[w] {
+				ids[id] = struct{}{}
+			}
+		}
+		if out == nil {
+			out = ids
+			continue
+		}
+		for id := range out {
+			if _, ok := ids[id]; !ok {
+				delete(out, id)
+			}
+		}
+	}
+	return out
+}
+
+// rankBooks mengurutkan hasil: skor tertinggi dulu (jika ada q), lalu keys.
+func rankBooks(items []Book, scores map[string]float64, keys []SortKey) {
+	sort.SliceStable(items, func(i, j int) bool {
+		si, sj := scores[items[i].ID], scores[items[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		return compareBooks(items[i], items[j], keys) < 0
+	})
+}
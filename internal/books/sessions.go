@@ -0,0 +1,157 @@
+package books
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type sessionInput struct {
+	StartPage int        `json:"startPage"`
+	EndPage   int        `json:"endPage"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"`
+}
+
+func validateSession(s *Session, pageCount int) error {
+	if s.StartPage < 0 || s.EndPage < s.StartPage {
+		return ErrInvalidSession
+	}
+	if s.EndPage > pageCount {
+		return ErrReadPageTooBig
+	}
+	if s.EndedAt != nil && s.EndedAt.Before(s.StartedAt) {
+		return ErrInvalidSession
+	}
+	return nil
+}
+
+// initialSession: readPage/reading yang dikirim saat buku dibuat (POST
+// /books, import) dicatat sebagai sesi baca, jadi progress tetap turunan
+// sesi terbaru. nil = belum ada yang dibaca. ID diisi store.
+func initialSession(b *Book, userID string, now time.Time) *Session {
+	if userID == "" || (b.ReadPage <= 0 && !b.Reading) {
+		return nil
+	}
+	s := &Session{BookID: b.ID, UserID: userID, StartPage: 0, EndPage: max(b.ReadPage, 0), StartedAt: now}
+	if !b.Reading {
+		s.EndedAt = &now
+	}
+	return s
+}
+
+// POST /books/{id}/sessions
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var in sessionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	if in.StartedAt.IsZero() {
+		in.StartedAt = time.Now()
+	}
+	s := &Session{
+		StartPage: in.StartPage,
+		EndPage:   in.EndPage,
+		StartedAt: in.StartedAt,
+		EndedAt:   in.EndedAt,
+	}
+	b, err := h.Store.AddSession(scope(r), chi.URLParam(r, "id"), s)
+	if err != nil {
+		switch err {
+		case ErrNotFound:
+			writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "id not found"})
+		case ErrInvalidSession:
+			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "startPage <= endPage and startedAt <= endedAt required"})
+		case ErrReadPageTooBig:
+			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "endPage must be <= pageCount"})
+		default:
+			log.Printf("[books.CreateSession] error: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		}
+		return
+	}
+	w.Header().Set("ETag", etag(b.Version))
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status": "success",
+		"data":   map[string]any{"session": s, "book": b},
+	})
+}
+
+// GET /books/{id}/sessions
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Store.ListSessions(scope(r), chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeSessionsError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"sessions": list}})
+}
+
+type timelinePoint struct {
+	SessionID string    `json:"sessionId"`
+	At        time.Time `json:"at"`
+	Page      int       `json:"page"`
+	PagesRead int       `json:"pagesRead"`
+	Progress  float64   `json:"progress"` // persen 0..100
+}
+
+// GET /books/{id}/timeline
+// Progres baca dari waktu ke waktu, diturunkan dari sesi (urut waktu mulai).
+func (h *Handler) Timeline(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	b, err := h.Store.Get(scope(r), id)
+	if err != nil {
+		h.writeSessionsError(w, ErrNotFound)
+		return
+	}
+	list, err := h.Store.ListSessions(scope(r), id)
+	if err != nil {
+		h.writeSessionsError(w, err)
+		return
+	}
+
+	points := make([]timelinePoint, 0, len(list))
+	var totalPages int
+	var spent time.Duration
+	for _, s := range list {
+		at := s.StartedAt
+		if s.EndedAt != nil {
+			at = *s.EndedAt
+			spent += s.EndedAt.Sub(s.StartedAt)
+		}
+		read := s.EndPage - s.StartPage
+		totalPages += read
+		var pct float64
+		if b.PageCount > 0 {
+			pct = float64(s.EndPage) * 100 / float64(b.PageCount)
+		}
+		points = append(points, timelinePoint{SessionID: s.ID, At: at, Page: s.EndPage, PagesRead: read, Progress: pct})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"timeline": points,
+			"summary": map[string]any{
+				"sessions":       len(list),
+				"pagesRead":      totalPages,
+				"readingSeconds": int(spent.Seconds()),
+				"readPage":       b.ReadPage,
+				"finished":       b.Finished,
+			},
+		},
+	})
+}
+
+func (h *Handler) writeSessionsError(w http.ResponseWriter, err error) {
+	if err == ErrNotFound {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "id not found"})
+		return
+	}
+	log.Printf("[books.sessions] error: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+}
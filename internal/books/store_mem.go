@@ -2,6 +2,7 @@ package books
 
 import (
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,32 +12,88 @@ var (
 	ErrNotFound          = errors.New("book not found")
 	ErrInvalidName       = errors.New("name is required")
 	ErrReadPageTooBig    = errors.New("readPage must be <= pageCount")
+	ErrVersionMismatch   = errors.New("book version mismatch")
+	ErrInvalidSession    = errors.New("invalid reading session")
+	ErrForbidden         = errors.New("only the owner can modify this book")
 )
 
+// Store: semua method menerima Scope pemanggil. Buku di luar library
+// pemanggil dilaporkan sbg ErrNotFound; mengubah metadata buku milik
+// orang lain -> ErrForbidden (kecuali admin).
 type Store interface {
-	Create(b *Book) (string, error)
-	Get(id string) (*Book, error)
-	List(filter Filter) ([]Book, int, error)
-	Update(id string, patch Book) error
-	Delete(id string) error
+	// Create: readPage/reading awal dicatat sbg sesi baca pemanggil (sama
+	// dgn Import), bukan ditulis langsung ke progress.
+	Create(sc Scope, b *Book) (string, error)
+	Get(sc Scope, id string) (*Book, error)
+	List(sc Scope, filter Filter) (Page, error)
+	// Each memanggil fn utk setiap buku hasil filter (tanpa Limit/Offset),
+	// berurutan; f.After melanjutkan dari cursor. Error dari fn menghentikan iterasi.
+	Each(sc Scope, filter Filter, fn func(Book) error) error
+	// ifVersion 0 = tanpa syarat; selain itu harus sama dgn versi saat ini
+	// atau ErrVersionMismatch.
+	Update(sc Scope, id string, patch Book, ifVersion int) (*Book, error)
+	Patch(sc Scope, id string, patch BookPatch, ifVersion int) (*Book, error)
+	Delete(sc Scope, id string, ifVersion int) error
+	// Import menyimpan banyak buku (sudah divalidasi) sekaligus, semua atau
+	// tidak sama sekali. id dikembalikan sesuai urutan input.
+	Import(sc Scope, books []Book) ([]string, error)
+
+	// AddSession mencatat sesi baca lalu menurunkan readPage/reading/finished
+	// milik pemanggil dari sesi terbarunya. Buku harus sudah terlihat oleh
+	// pemanggil (ErrNotFound jika tidak).
+	AddSession(sc Scope, bookID string, s *Session) (*Book, error)
+	ListSessions(sc Scope, bookID string) ([]Session, error)
 }
 
+// Filter utk List. Field kosong/nil = diabaikan; lihat ParseFilter utk
+// nama param query-nya.
 type Filter struct {
+	Q        string // full-text: name/author/publisher, lihat parseSearch
 	Name     string
 	Reading  *bool // nil = ignore
 	Finished *bool // nil = ignore
+	Author     string
+	Publisher  string
+	PageCount  IntRange
+	ReadPage   IntRange
+	Progress   FloatRange // persen 0..100
+	InsertedAt TimeRange
+	UpdatedAt  TimeRange
+	IDs        []string
+	Sort       []SortKey // kosong = defaultSort; Q mengurutkan per rank dulu
+	Fields     []string  // kolom yang perlu diisi (nil = semua); pgStore hanya SELECT ini
 	Limit 	  int
 	Offset 	  int
+	// keyset pagination; tidak dipakai bersamaan dengan Q (urutan rank)
+	After    *Cursor
+	Before   *Cursor
+	Count    string // CountExact (default) | CountEstimate | CountNone
+}
+
+type progressKey struct{ book, user string }
+
+type progress struct {
+	ReadPage int
+	Reading  bool
+	Finished bool
 }
 
 type memStore struct {
-	mu    sync.RWMutex
-	items map[string]Book
-	idSeq int64
+	mu       sync.RWMutex
+	items    map[string]Book // metadata saja; state baca ada di progress
+	progress map[progressKey]progress
+	sessions map[string][]Session // per book, urut StartedAt naik
+	index    invertedIndex
+	idSeq    int64
 }
 
 func NewMemStore() Store {
-	return &memStore{items: make(map[string]Book)}
+	return &memStore{
+		items:    make(map[string]Book),
+		progress: make(map[progressKey]progress),
+		sessions: make(map[string][]Session),
+		index:    invertedIndex{},
+	}
 }
 
 func (m *memStore) nextID() string {
@@ -44,7 +101,32 @@ func (m *memStore) nextID() string {
 	return randomID() // stable random id helper below
 }
 
-func (m *memStore) Create(b *Book) (string, error) {
+func (m *memStore) visible(sc Scope, b Book) bool {
+	if b.OrgID != sc.OrgID {
+		return false
+	}
+	if sc.Admin || (sc.UserID != "" && b.OwnerID == sc.UserID) {
+		return true
+	}
+	_, ok := m.progress[progressKey{b.ID, sc.UserID}]
+	return ok
+}
+
+// view = metadata buku + state baca milik sc.UserID.
+func (m *memStore) view(sc Scope, b Book) Book {
+	p, ok := m.progress[progressKey{b.ID, sc.UserID}]
+	if !ok {
+		p = progress{Finished: b.PageCount == 0}
+	}
+	b.ReadPage, b.Reading, b.Finished = p.ReadPage, p.Reading, p.Finished
+	return b
+}
+
+func canEdit(sc Scope, ownerID string) bool {
+	return sc.Admin || (sc.UserID != "" && ownerID == sc.UserID)
+}
+
+func (m *memStore) Create(sc Scope, b *Book) (string, error) {
 	if strings.TrimSpace(b.Name) == "" {
 		return "", ErrInvalidName
 	}
@@ -52,90 +134,234 @@ func (m *memStore) Create(b *Book) (string, error) {
 		return "", ErrReadPageTooBig
 	}
 
-	now := time.Now()
+	m.mu.Lock()
+	m.insert(sc, b, time.Now())
+	m.mu.Unlock()
+	return b.ID, nil
+}
+
+// insert: pemanggil memegang m.mu.
+func (m *memStore) insert(sc Scope, b *Book, now time.Time) {
 	b.ID = m.nextID()
+	b.OwnerID = sc.UserID
+	b.OrgID = sc.OrgID
 	b.Finished = b.PageCount == b.ReadPage
 	b.InsertedAt = now
 	b.UpdatedAt = now
+	b.Version = 1
 
-	m.mu.Lock()
 	m.items[b.ID] = *b
-	m.mu.Unlock()
-	return b.ID, nil
+	m.index.add(*b)
+	if sc.UserID != "" {
+		m.progress[progressKey{b.ID, sc.UserID}] = progress{b.ReadPage, b.Reading, b.Finished}
+	}
+	if s := initialSession(b, sc.UserID, now); s != nil {
+		s.ID = randomID()
+		m.sessions[b.ID] = []Session{*s}
+	}
+}
+
+// Import: semua divalidasi dulu lalu disimpan dalam satu lock, sama dgn
+// transaksi pgStore (semua atau tidak sama sekali, tanpa terlihat setengah).
+func (m *memStore) Import(sc Scope, books []Book) ([]string, error) {
+	for i := range books {
+		if err := validate(&books[i]); err != nil {
+			return nil, err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	ids := make([]string, len(books))
+	for i := range books {
+		m.insert(sc, &books[i], now)
+		ids[i] = books[i].ID
+	}
+	return ids, nil
 }
 
-func (m *memStore) Get(id string) (*Book, error) {
+func (m *memStore) Get(sc Scope, id string) (*Book, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	v, ok := m.items[id]
-	if !ok {
+	if !ok || !m.visible(sc, v) {
 		return nil, ErrNotFound
 	}
+	v = m.view(sc, v)
 	return &v, nil
 }
 
-func (m *memStore) List(f Filter) ([]Book, int, error) {
+func (m *memStore) List(sc Scope, f Filter) (Page, error) {
   m.mu.RLock()
   defer m.mu.RUnlock()
 
+  sq := parseSearch(f.Q)
+  cands := m.index.candidates(sq)
+  scores := map[string]float64{}
+
   // filter
+  preds := f.predicates()
   tmp := make([]Book, 0, len(m.items))
-  needle := strings.ToLower(strings.TrimSpace(f.Name))
   for _, b := range m.items {
-    if needle != "" && !strings.Contains(strings.ToLower(b.Name), needle) {
-      continue
+    if !m.visible(sc, b) { continue }
+    if len(sq) > 0 {
+      if _, ok := cands[b.ID]; cands != nil && !ok { continue }
+      s, ok := sq.score(b)
+      if !ok { continue }
+      scores[b.ID] = s
+      b.Snippet = sq.highlight(b)
     }
-    if f.Reading != nil && b.Reading != *f.Reading { continue }
-    if f.Finished != nil && b.Finished != *f.Finished { continue }
+    b = m.view(sc, b)
+    if !matchAll(preds, b) { continue }
     tmp = append(tmp, b)
   }
-    total := len(tmp)
+    rankBooks(tmp, scores, f.sortKeys())
 
-    start := f.Offset
-    if start < 0 { start = 0 }
-    if start > total { start = total }
-
-    end := start + f.Limit
-    if f.Limit <= 0 || end > total { end = total }
+    pg := paginate(tmp, f)
+    pg.Total = len(tmp) // estimate == exact di memori
+    if f.Count == CountNone { pg.Total = -1 }
+    return pg, nil
+}
 
-    return tmp[start:end], total, nil
+func (m *memStore) Each(sc Scope, f Filter, fn func(Book) error) error {
+	f.Limit, f.Offset, f.Before, f.Count = 0, 0, nil, CountNone
+	pg, err := m.List(sc, f)
+	if err != nil {
+		return err
+	}
+	for _, b := range pg.Items {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (m *memStore) Update(id string, patch Book) error {
+func (m *memStore) Update(sc Scope, id string, patch Book, ifVersion int) (*Book, error) {
 	if strings.TrimSpace(patch.Name) == "" {
-		return ErrInvalidName
-	}
-	if patch.ReadPage > patch.PageCount {
-		return ErrReadPageTooBig
+		return nil, ErrInvalidName
 	}
+	return m.Patch(sc, id, fullPatch(patch), ifVersion)
+}
+
+func (m *memStore) Patch(sc Scope, id string, patch BookPatch, ifVersion int) (*Book, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	old, ok := m.items[id]
-	if !ok {
-		return ErrNotFound
+	b, ok := m.items[id]
+	if !ok || !m.visible(sc, b) {
+		return nil, ErrNotFound
 	}
-	old.Name = patch.Name
-	old.Author = patch.Author
-	old.Publisher = patch.Publisher
-	old.PageCount = patch.PageCount
-	old.ReadPage = patch.ReadPage
-	old.Reading = patch.Reading
-	old.Finished = patch.PageCount == patch.ReadPage
-	old.UpdatedAt = time.Now()
-	m.items[id] = old
-	return nil
+	if patch.touchesMeta() && !canEdit(sc, b.OwnerID) {
+		return nil, ErrForbidden
+	}
+	if ifVersion != 0 && b.Version != ifVersion {
+		return nil, ErrVersionMismatch
+	}
+	cur := m.view(sc, b)
+	patch.applyTo(&cur)
+	if err := validate(&cur); err != nil {
+		return nil, err
+	}
+
+	if patch.PageCount != nil {
+		// progress pembaca lain tidak pernah ditulis ulang: pageCount di
+		// bawah readPage siapa pun ditolak
+		for k, p := range m.progress {
+			if k.book == id && p.ReadPage > cur.PageCount {
+				return nil, ErrReadPageTooBig
+			}
+		}
+		for k, p := range m.progress {
+			if k.book == id {
+				p.Finished = p.ReadPage == cur.PageCount
+				m.progress[k] = p
+			}
+		}
+	}
+	m.index.remove(b)
+	b.Name, b.Author, b.Publisher, b.PageCount = cur.Name, cur.Author, cur.Publisher, cur.PageCount
+	m.index.add(b)
+	b.UpdatedAt = time.Now()
+	b.Version++
+	m.items[id] = b
+	cur.UpdatedAt, cur.Version = b.UpdatedAt, b.Version
+	return &cur, nil
 }
 
-func (m *memStore) Delete(id string) error {
+func (m *memStore) Delete(sc Scope, id string, ifVersion int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if _, ok := m.items[id]; !ok {
+	b, ok := m.items[id]
+	if !ok || !m.visible(sc, b) {
 		return ErrNotFound
 	}
+	if !canEdit(sc, b.OwnerID) {
+		return ErrForbidden
+	}
+	if ifVersion != 0 && b.Version != ifVersion {
+		return ErrVersionMismatch
+	}
+	m.index.remove(b)
 	delete(m.items, id)
+	delete(m.sessions, id)
+	for k := range m.progress {
+		if k.book == id {
+			delete(m.progress, k)
+		}
+	}
 	return nil
 }
 
+func (m *memStore) AddSession(sc Scope, bookID string, s *Session) (*Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.items[bookID]
+	if !ok || !m.visible(sc, b) {
+		return nil, ErrNotFound
+	}
+	if err := validateSession(s, b.PageCount); err != nil {
+		return nil, err
+	}
+	s.ID = randomID()
+	s.BookID = bookID
+	s.UserID = sc.UserID
+
+	list := append(m.sessions[bookID], *s)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	m.sessions[bookID] = list
+
+	// sesi terbaru milik user ini
+	var latest Session
+	for _, x := range list {
+		if x.UserID == sc.UserID {
+			latest = x
+		}
+	}
+	p := progress{ReadPage: latest.EndPage, Reading: latest.EndedAt == nil}
+	p.Finished = p.ReadPage == b.PageCount
+	// progress per user: versi/ETag buku tidak berubah
+	m.progress[progressKey{bookID, sc.UserID}] = p
+
+	v := m.view(sc, b)
+	return &v, nil
+}
+
+func (m *memStore) ListSessions(sc Scope, bookID string) ([]Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	b, ok := m.items[bookID]
+	if !ok || !m.visible(sc, b) {
+		return nil, ErrNotFound
+	}
+	out := []Session{}
+	for _, s := range m.sessions[bookID] {
+		if sc.Admin || s.UserID == sc.UserID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
 // --- helpers ---
 
 // simple URL-safe random id
@@ -3,21 +3,27 @@ package books
 import (
 	"context"
 	"errors"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ImamSR/go-books-api/internal/util"
 )
 
 type pgStore struct {
 	pool *pgxpool.Pool
+	rls  bool
 }
 
-func NewPGStore(pool *pgxpool.Pool) Store {
-	return &pgStore{pool: pool}
+// NewPGStore: rls=true jika role DB tunduk pada policy row-level security
+// books (bukan pemilik tabel); tenant pemanggil lalu diset ke app.org_id di
+// setiap transaksi, sebagai lapisan kedua di samping filter org_id di query.
+func NewPGStore(pool *pgxpool.Pool, rls bool) Store {
+	return &pgStore{pool: pool, rls: rls}
 }
 
 func isUniqueViolation(err error) bool {
@@ -25,7 +31,77 @@ func isUniqueViolation(err error) bool {
 	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
 
-func (p *pgStore) Create(b *Book) (string, error) {
+// Semua query baca memakai $1 = user id pemanggil, $2 = admin, $3 = tenant
+// (org id, "" = ruang pribadi). Buku tenant lain tidak pernah terlihat.
+const (
+	bookCols = `b.id, COALESCE(b.owner_id, ''), b.name, COALESCE(b.author, ''), COALESCE(b.publisher, ''), b.page_count,
+	       COALESCE(p.read_page, 0), COALESCE(p.reading, FALSE), COALESCE(p.finished, b.page_count = 0),
+	       b.inserted_at, b.updated_at, b.version`
+	bookFrom     = `books b LEFT JOIN book_progress p ON p.book_id = b.id AND p.user_id = $1`
+	visibleWhere = `COALESCE(b.org_id, '') = $3 AND ($2 OR b.owner_id = $1 OR p.user_id IS NOT NULL)`
+)
+
+// querier = pool atau tx.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// begin: transaksi; dgn RLS tenant pemanggil diset utk transaksi ini saja.
+func (p *pgStore) begin(ctx context.Context, sc Scope) (pgx.Tx, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.rls {
+		if _, err := tx.Exec(ctx, `SELECT set_config('app.org_id', $1, true)`, sc.OrgID); err != nil {
+			tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// read menjalankan fn langsung di pool; dgn RLS di transaksi yang
+// di-rollback setelahnya (hanya baca).
+func (p *pgStore) read(ctx context.Context, sc Scope, fn func(q querier) error) error {
+	if !p.rls {
+		return fn(p.pool)
+	}
+	tx, err := p.begin(ctx, sc)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	return fn(tx)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBook(row scanner, b *Book) error {
+	return row.Scan(&b.ID, &b.OwnerID, &b.Name, &b.Author, &b.Publisher, &b.PageCount,
+		&b.ReadPage, &b.Reading, &b.Finished, &b.InsertedAt, &b.UpdatedAt, &b.Version)
+}
+
+func getBook(ctx context.Context, q querier, sc Scope, id string, lock bool) (*Book, error) {
+	sql := `SELECT ` + bookCols + ` FROM ` + bookFrom + ` WHERE b.id = $4 AND ` + visibleWhere
+	if lock {
+		sql += ` FOR UPDATE OF b`
+	}
+	var b Book
+	err := scanBook(q.QueryRow(ctx, sql, sc.UserID, sc.Admin, sc.OrgID, id), &b)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (p *pgStore) Create(sc Scope, b *Book) (string, error) {
 	if strings.TrimSpace(b.Name) == "" {
 		return "", ErrInvalidName
 	}
@@ -41,132 +117,473 @@ func (p *pgStore) Create(b *Book) (string, error) {
 
 	for attempt := 0; attempt < 3; attempt++ {
 		id = util.RandomID()
-		_, err = p.pool.Exec(context.Background(),
-			`INSERT INTO books
-			   (id, name, author, publisher, page_count, read_page, reading, finished, inserted_at, updated_at)
-			 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
-			id, b.Name, b.Author, b.Publisher, b.PageCount, b.readPageOrZero(),
-			b.Reading, finished, now, now,
-		)
+		err = p.insert(sc, id, b, finished, now)
 		if err == nil {
 			return id, nil
 		}
 		if !isUniqueViolation(err) {
 			return "", err
 		}
-		
+
 	}
 
 	return "", err
 }
 
-func (p *pgStore) Get(id string) (*Book, error) {
-	row := p.pool.QueryRow(context.Background(),
-		`SELECT id, name, author, publisher, page_count, read_page, reading, finished, inserted_at, updated_at
-         FROM books WHERE id = $1`, id)
+func (p *pgStore) insert(sc Scope, id string, b *Book, finished bool, now time.Time) error {
+	ctx := context.Background()
+	tx, err := p.begin(ctx, sc)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
-	var b Book
-	err := row.Scan(&b.ID, &b.Name, &b.Author, &b.Publisher, &b.PageCount, &b.ReadPage, &b.Reading, &b.Finished, &b.InsertedAt, &b.UpdatedAt)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO books
+		   (id, owner_id, org_id, name, author, publisher, page_count, inserted_at, updated_at)
+		 VALUES ($1,NULLIF($2,''),NULLIF($3,''),$4,$5,$6,$7,$8,$9)`,
+		id, sc.UserID, sc.OrgID, b.Name, b.Author, b.Publisher, b.PageCount, now, now,
+	); err != nil {
+		return err
+	}
+	if sc.UserID != "" {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO book_progress (book_id, user_id, read_page, reading, finished, updated_at)
+			 VALUES ($1,$2,$3,$4,$5,$6)`,
+			id, sc.UserID, b.readPageOrZero(), b.Reading, finished, now,
+		); err != nil {
+			return err
+		}
+	}
+	b.ID = id
+	if s := initialSession(b, sc.UserID, now); s != nil {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO reading_sessions (id, book_id, user_id, start_page, end_page, started_at, ended_at)
+			 VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+			util.RandomID(), id, s.UserID, s.StartPage, s.EndPage, s.StartedAt, s.EndedAt,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Import memakai COPY (CopyFrom) dalam satu transaksi. Postgres menolak
+// COPY FROM ke tabel ber-RLS, jadi dgn RLS baris books di-INSERT per batch.
+func (p *pgStore) Import(sc Scope, books []Book) ([]string, error) {
+	for i := range books {
+		if err := validate(&books[i]); err != nil {
+			return nil, err
+		}
+	}
+	ctx := context.Background()
+	tx, err := p.begin(ctx, sc)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
-	return &b, nil
+	defer tx.Rollback(ctx)
+
+	var owner, org any
+	if sc.UserID != "" {
+		owner = sc.UserID
+	}
+	if sc.OrgID != "" {
+		org = sc.OrgID
+	}
+	now := time.Now()
+	ids := make([]string, len(books))
+	bookRows := make([][]any, len(books))
+	progRows := make([][]any, 0, len(books))
+	var sessRows [][]any
+	for i, b := range books {
+		ids[i] = util.RandomID()
+		bookRows[i] = []any{ids[i], owner, org, b.Name, b.Author, b.Publisher, b.PageCount, now, now}
+		if owner != nil {
+			progRows = append(progRows, []any{ids[i], owner, b.readPageOrZero(), b.Reading, b.PageCount == b.ReadPage, now})
+		}
+		b.ID = ids[i]
+		if s := initialSession(&b, sc.UserID, now); s != nil {
+			sessRows = append(sessRows, []any{util.RandomID(), s.BookID, s.UserID, s.StartPage, s.EndPage, s.StartedAt, s.EndedAt})
+		}
+	}
+	cols := []string{"id", "owner_id", "org_id", "name", "author", "publisher", "page_count", "inserted_at", "updated_at"}
+	if p.rls {
+		batch := &pgx.Batch{}
+		for _, row := range bookRows {
+			batch.Queue(`INSERT INTO books (`+strings.Join(cols, ", ")+`) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`, row...)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, err
+		}
+	} else if _, err := tx.CopyFrom(ctx, pgx.Identifier{"books"}, cols, pgx.CopyFromRows(bookRows)); err != nil {
+		return nil, err
+	}
+	if len(progRows) > 0 {
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"book_progress"},
+			[]string{"book_id", "user_id", "read_page", "reading", "finished", "updated_at"},
+			pgx.CopyFromRows(progRows),
+		); err != nil {
+			return nil, err
+		}
+	}
+	if len(sessRows) > 0 {
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"reading_sessions"},
+			[]string{"id", "book_id", "user_id", "start_page", "end_page", "started_at", "ended_at"},
+			pgx.CopyFromRows(sessRows),
+		); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
-func (p *pgStore) List(f Filter) ([]Book, int, error) {
+func (p *pgStore) Get(sc Scope, id string) (b *Book, err error) {
+	ctx := context.Background()
+	err = p.read(ctx, sc, func(q querier) error {
+		b, err = getBook(ctx, q, sc, id, false)
+		return err
+	})
+	return b, err
+}
+
+// listQuery = bagian query yang sama utk List dan Each.
+type listQuery struct {
+  keys    []SortKey
+  sel     []bookField
+  cols    string // kolom SELECT (termasuk version, dan snippet jika ada q)
+  where   string
+  orderBy string
+  args    sqlArgs
+  snippet bool
+}
+
+func buildListQuery(sc Scope, f Filter) *listQuery {
+  lq := &listQuery{keys: f.sortKeys(), args: sqlArgs{sc.UserID, sc.Admin, sc.OrgID}}
+  lq.sel = selectFields(f.Fields, lq.keys)
+  cols := make([]string, len(lq.sel))
+  for i, fd := range lq.sel { cols[i] = fd.col }
+  // version selalu ikut (ETag), sama dgn Get/memStore
+  lq.cols = strings.Join(cols, ", ") + ", b.version"
   // base filter
-  where := "WHERE 1=1"
-  args := []any{}
-  i := 1
-  if strings.TrimSpace(f.Name) != "" {
-    where += " AND lower(name) LIKE $" + strconv.Itoa(i)
-    args = append(args, "%"+strings.ToLower(strings.TrimSpace(f.Name))+"%")
-    i++
-  }
-  if f.Reading != nil {
-    where += " AND reading = $" + strconv.Itoa(i)
-    args = append(args, *f.Reading)
-    i++
+  lq.where = "WHERE " + visibleWhere
+  lq.orderBy = orderBySQL(lq.keys, false)
+  if sq := parseSearch(f.Q); len(sq) > 0 {
+    ph := "to_tsquery('simple', " + lq.args.add(sq.tsquery()) + ")"
+    lq.where += " AND b.search @@ " + ph
+    lq.cols += `, ts_headline('simple', concat_ws(' - ', NULLIF(b.name, ''), NULLIF(b.author, ''), NULLIF(b.publisher, '')), ` + ph +
+      `, '` + headlineOpts + `')`
+    lq.orderBy = "ts_rank(b.search, " + ph + ") DESC, " + lq.orderBy
+    lq.snippet = true
   }
-  if f.Finished != nil {
-    where += " AND finished = $" + strconv.Itoa(i)
-    args = append(args, *f.Finished)
-    i++
+  for _, pr := range f.predicates() {
+    lq.where += " AND " + pr.sql(&lq.args)
   }
+  return lq
+}
+
+func (lq *listQuery) scan(rows pgx.Rows) (Book, error) {
+  var b Book
+  dest := make([]any, 0, len(lq.sel)+2)
+  for _, fd := range lq.sel { dest = append(dest, fd.ref(&b)) }
+  dest = append(dest, &b.Version)
+  if lq.snippet { dest = append(dest, &b.Snippet) }
+  err := rows.Scan(dest...)
+  if lq.snippet { b.Snippet = snippetHTML(b.Snippet) }
+  return b, err
+}
+
+func (p *pgStore) List(sc Scope, f Filter) (pg Page, err error) {
+  ctx := context.Background()
+  err = p.read(ctx, sc, func(q querier) error {
+    pg, err = list(ctx, q, sc, f)
+    return err
+  })
+  return pg, err
+}
+
+func list(ctx context.Context, db querier, sc Scope, f Filter) (Page, error) {
+  lq := buildListQuery(sc, f)
+  keys, args, where, orderBy := lq.keys, lq.args, lq.where, lq.orderBy
 
   // total
-  var total int
-  if err := p.pool.QueryRow(context.Background(),
-    "SELECT COUNT(*) FROM books "+where, args...,
-  ).Scan(&total); err != nil {
-    return nil, 0, err
+  pg := Page{Total: -1}
+  switch f.Count {
+  case CountNone:
+  case CountEstimate:
+    n, err := estimate(ctx, db)
+    if err != nil { return Page{}, err }
+    pg.Total = n
+  default:
+    if err := db.QueryRow(ctx,
+      "SELECT COUNT(*) FROM "+bookFrom+" "+where, args...,
+    ).Scan(&pg.Total); err != nil {
+      return Page{}, err
+    }
   }
 
-  // page
+  // page: keyset (after/before) atau offset; ambil limit+1 utk tahu ada sisa
   limit := f.Limit
   if limit <= 0 { limit = 10 }
   offset := f.Offset
   if offset < 0 { offset = 0 }
 
+  reverse := false
+  switch {
+  case f.After != nil:
+    where += " AND " + keysetSQL(keys, f.After, false, &args)
+    offset = 0
+  case f.Before != nil:
+    where += " AND " + keysetSQL(keys, f.Before, true, &args)
+    orderBy, reverse, offset = orderBySQL(keys, true), true, 0
+  }
+
   q := `
-    SELECT id, name, author, publisher, page_count, read_page, reading, finished, inserted_at, updated_at
-    FROM books ` + where + `
-    ORDER BY inserted_at DESC
-    LIMIT $` + strconv.Itoa(i) + ` OFFSET $` + strconv.Itoa(i+1)
+    SELECT ` + lq.cols + `
+    FROM ` + bookFrom + ` ` + where + `
+    ORDER BY ` + orderBy + `
+    LIMIT ` + args.add(limit+1) + ` OFFSET ` + args.add(offset)
 
-  rows, err := p.pool.Query(context.Background(), q, append(args, limit, offset)...)
-  if err != nil { return nil, 0, err }
+  rows, err := db.Query(ctx, q, args...)
+  if err != nil { return Page{}, err }
   defer rows.Close()
 
   var out []Book
   for rows.Next() {
-    var b Book
-    if err := rows.Scan(&b.ID, &b.Name, &b.Author, &b.Publisher, &b.PageCount, &b.ReadPage, &b.Reading, &b.Finished, &b.InsertedAt, &b.UpdatedAt); err != nil {
-      return nil, 0, err
+    b, err := lq.scan(rows)
+    if err != nil {
+      return Page{}, err
     }
     out = append(out, b)
   }
-  return out, total, nil
+  if err := rows.Err(); err != nil { return Page{}, err }
+
+  more := len(out) > limit
+  if more { out = out[:limit] }
+  if reverse {
+    slices.Reverse(out)
+    pg.HasPrev, pg.HasNext = more, true
+  } else {
+    pg.HasNext, pg.HasPrev = more, f.After != nil || offset > 0
+  }
+  pg.Items = out
+  return pg, nil
+}
+
+// Each men-stream seluruh hasil filter (tanpa limit) baris demi baris
+// langsung dari rows pgx, tanpa menampung semuanya di memori.
+// f.After dipakai utk melanjutkan export yang terputus.
+func (p *pgStore) Each(sc Scope, f Filter, fn func(Book) error) error {
+  ctx := context.Background()
+  lq := buildListQuery(sc, f)
+  where := lq.where
+  if f.After != nil {
+    where += " AND " + keysetSQL(lq.keys, f.After, false, &lq.args)
+  }
+  return p.read(ctx, sc, func(db querier) error {
+    rows, err := db.Query(ctx,
+      `SELECT `+lq.cols+` FROM `+bookFrom+` `+where+` ORDER BY `+lq.orderBy, lq.args...)
+    if err != nil { return err }
+    defer rows.Close()
+    for rows.Next() {
+      b, err := lq.scan(rows)
+      if err != nil { return err }
+      if err := fn(b); err != nil { return err }
+    }
+    return rows.Err()
+  })
+}
+
+// estimate: perkiraan kasar dari statistik tabel (pg_class.reltuples),
+// tanpa filter/tenant; pakai count=exact utk angka tepat.
+func estimate(ctx context.Context, db querier) (int, error) {
+  var n float64
+  if err := db.QueryRow(ctx,
+    `SELECT reltuples FROM pg_class WHERE oid = 'books'::regclass`,
+  ).Scan(&n); err != nil {
+    return 0, err
+  }
+  if n < 0 { return 0, nil } // -1 = belum pernah di-ANALYZE
+  return int(n), nil
 }
 
-func (p *pgStore) Update(id string, patch Book) error {
+func (p *pgStore) Update(sc Scope, id string, patch Book, ifVersion int) (*Book, error) {
 	if strings.TrimSpace(patch.Name) == "" {
-		return ErrInvalidName
+		return nil, ErrInvalidName
 	}
-	if patch.ReadPage > patch.PageCount {
-		return ErrReadPageTooBig
+	return p.Patch(sc, id, fullPatch(patch), ifVersion)
+}
+
+// Patch membaca baris dengan FOR UPDATE, merge, validasi ulang, lalu simpan.
+// Metadata hanya boleh diubah pemilik/admin; readPage/reading hanya lewat sesi baca.
+func (p *pgStore) Patch(sc Scope, id string, patch BookPatch, ifVersion int) (*Book, error) {
+	ctx := context.Background()
+	tx, err := p.begin(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	b, err := getBook(ctx, tx, sc, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if patch.touchesMeta() && !canEdit(sc, b.OwnerID) {
+		return nil, ErrForbidden
+	}
+	if ifVersion != 0 && b.Version != ifVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	patch.applyTo(b)
+	if err := validate(b); err != nil {
+		return nil, err
+	}
+	if patch.PageCount != nil {
+		// progress pembaca lain tidak pernah ditulis ulang: pageCount di
+		// bawah readPage siapa pun ditolak
+		var maxRead int
+		if err := tx.QueryRow(ctx,
+			`SELECT COALESCE(MAX(read_page), 0) FROM book_progress WHERE book_id = $1`, id,
+		).Scan(&maxRead); err != nil {
+			return nil, err
+		}
+		if maxRead > b.PageCount {
+			return nil, ErrReadPageTooBig
+		}
 	}
-	now := time.Now()
-	finished := patch.PageCount == patch.ReadPage
 
-	ct, err := p.pool.Exec(context.Background(),
+	if err := tx.QueryRow(ctx,
 		`UPDATE books
-		   SET name=$1, author=$2, publisher=$3,
-		       page_count=$4, read_page=$5,
-		       reading=$6, finished=$7, updated_at=$8
-		 WHERE id=$9`,
-		patch.Name, patch.Author, patch.Publisher,
-		patch.PageCount, patch.readPageOrZero(),
-		patch.Reading, finished, now, id,
-	)
+		   SET name=$1, author=$2, publisher=$3, page_count=$4,
+		       updated_at=NOW(), version=version+1
+		 WHERE id=$5 AND COALESCE(org_id, '') = $6
+		 RETURNING updated_at, version`,
+		b.Name, b.Author, b.Publisher, b.PageCount, id, sc.OrgID,
+	).Scan(&b.UpdatedAt, &b.Version); err != nil {
+		return nil, err
+	}
+	if patch.PageCount != nil {
+		if _, err := tx.Exec(ctx,
+			`UPDATE book_progress SET finished = read_page = $2 WHERE book_id = $1`,
+			id, b.PageCount,
+		); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (p *pgStore) Delete(sc Scope, id string, ifVersion int) error {
+	ctx := context.Background()
+	tx, err := p.begin(ctx, sc)
 	if err != nil {
 		return err
 	}
-	if ct.RowsAffected() == 0 {
-		return ErrNotFound
+	defer tx.Rollback(ctx)
+
+	b, err := getBook(ctx, tx, sc, id, true)
+	if err != nil {
+		return err
+	}
+	if !canEdit(sc, b.OwnerID) {
+		return ErrForbidden
 	}
-	return nil
+	if ifVersion != 0 && b.Version != ifVersion {
+		return ErrVersionMismatch
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM books WHERE id = $1 AND COALESCE(org_id, '') = $2`, id, sc.OrgID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (p *pgStore) Delete(id string) error {
-	ct, err := p.pool.Exec(context.Background(), `DELETE FROM books WHERE id = $1`, id)
+func (p *pgStore) AddSession(sc Scope, bookID string, s *Session) (*Book, error) {
+	ctx := context.Background()
+	tx, err := p.begin(ctx, sc)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if ct.RowsAffected() == 0 {
-		return ErrNotFound
+	defer tx.Rollback(ctx)
+
+	// hanya buku di library pemanggil (pemilik, admin, atau sudah dilacak)
+	cur, err := getBook(ctx, tx, sc, bookID, true)
+	if err != nil {
+		return nil, err
+	}
+	pageCount := cur.PageCount
+	if err := validateSession(s, pageCount); err != nil {
+		return nil, err
+	}
+	s.ID = util.RandomID()
+	s.BookID = bookID
+	s.UserID = sc.UserID
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO reading_sessions (id, book_id, user_id, start_page, end_page, started_at, ended_at)
+		 VALUES ($1,$2,NULLIF($3,''),$4,$5,$6,$7)`,
+		s.ID, s.BookID, s.UserID, s.StartPage, s.EndPage, s.StartedAt, s.EndedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	// turunkan state baca pemanggil dari sesi terbarunya; versi/ETag buku
+	// tidak berubah karena progress milik per user
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO book_progress (book_id, user_id, read_page, reading, finished, updated_at)
+		 SELECT s.book_id, s.user_id, s.end_page, s.ended_at IS NULL, s.end_page = $3, NOW()
+		   FROM reading_sessions s
+		  WHERE s.book_id = $1 AND s.user_id = $2
+		  ORDER BY s.started_at DESC LIMIT 1
+		 ON CONFLICT (book_id, user_id) DO UPDATE
+		   SET read_page=EXCLUDED.read_page, reading=EXCLUDED.reading,
+		       finished=EXCLUDED.finished, updated_at=EXCLUDED.updated_at`,
+		bookID, sc.UserID, pageCount,
+	); err != nil {
+		return nil, err
+	}
+	b, err := getBook(ctx, tx, sc, bookID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
-	return nil
+	return b, nil
+}
+
+func (p *pgStore) ListSessions(sc Scope, bookID string) (out []Session, err error) {
+	ctx := context.Background()
+	err = p.read(ctx, sc, func(q querier) error {
+		if _, err := getBook(ctx, q, sc, bookID, false); err != nil {
+			return err
+		}
+		rows, err := q.Query(ctx,
+			`SELECT s.id, s.book_id, COALESCE(s.user_id, ''), s.start_page, s.end_page, s.started_at, s.ended_at
+			   FROM reading_sessions s JOIN books b ON b.id = s.book_id
+			  WHERE s.book_id = $1 AND COALESCE(b.org_id, '') = $4 AND ($2 OR s.user_id = $3)
+			  ORDER BY s.started_at`, bookID, sc.Admin, sc.UserID, sc.OrgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		out = []Session{}
+		for rows.Next() {
+			var s Session
+			if err := rows.Scan(&s.ID, &s.BookID, &s.UserID, &s.StartPage, &s.EndPage, &s.StartedAt, &s.EndedAt); err != nil {
+				return err
+			}
+			out = append(out, s)
+		}
+		return rows.Err()
+	})
+	return out, err
 }
 
 // helpers
@@ -1,8 +1,12 @@
 package httpx
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -18,3 +22,64 @@ func CommonMiddlewares(next http.Handler) http.Handler {
 	_ = middleware.RequestID
 	return chain
 }
+
+// TrustedProxiesFromEnv: TRUSTED_PROXIES = daftar IP/CIDR dipisah koma
+// (reverse proxy / load balancer yang boleh mengisi X-Forwarded-For).
+func TrustedProxiesFromEnv() ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, s := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			a, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+			}
+			out = append(out, netip.PrefixFrom(a, a.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// RealIP: jika koneksi datang dari proxy tepercaya, RemoteAddr diganti
+// alamat klien dari X-Forwarded-For (hop paling kanan yang bukan proxy
+// tepercaya; hop kiri bisa dipalsukan klien). Selain itu header diabaikan.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(a netip.Addr) bool {
+		a = a.Unmap()
+		for _, p := range trusted {
+			if p.Contains(a) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || !isTrusted(peer.Addr()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				a, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					break
+				}
+				if !isTrusted(a) || i == 0 {
+					r.RemoteAddr = netip.AddrPortFrom(a.Unmap(), 0).String()
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,16 +1,42 @@
 package httpx
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/netip"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/ImamSR/go-books-api/internal/audit"
 	"github.com/ImamSR/go-books-api/internal/books"
 	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/orgs"
+	"github.com/ImamSR/go-books-api/internal/rbac"
 	"github.com/ImamSR/go-books-api/internal/users"
 )
 
-func NewRouter(bh *books.Handler, uh *users.Handler) http.Handler {
+// Deps = semua handler/komponen yang dirangkai router.
+type Deps struct {
+	Books   *books.Handler
+	Users   *users.Handler
+	Orgs    *orgs.Handler
+	Audit   *audit.Log
+	RBAC    *rbac.Handler
+	Perms   *rbac.Resolver
+	Keys    *auth.KeySet
+	Revoked *auth.Revocations
+	PATs    auth.PATStore
+	// Cookies: sesi browser via cookie (nil = hanya header Authorization).
+	Cookies *auth.Cookies
+	// TrustedProxies: proxy yang X-Forwarded-For-nya dipercaya (kosong = pakai RemoteAddr).
+	TrustedProxies []netip.Prefix
+}
+
+func NewRouter(d Deps) http.Handler {
+	bh, uh, oh, ks := d.Books, d.Users, d.Orgs, d.Keys
 	r := chi.NewRouter()
+	if len(d.TrustedProxies) > 0 {
+		r.Use(RealIP(d.TrustedProxies))
+	}
 	r.Use(func(next http.Handler) http.Handler { return CommonMiddlewares(next) })
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -18,23 +44,115 @@ func NewRouter(bh *books.Handler, uh *users.Handler) http.Handler {
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	// public key utk layanan lain yang memverifikasi token kita
+	r.Get("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(map[string]any{"keys": ks.JWKS()})
+	})
+
+	authJWT := auth.AuthJWT(ks, d.Revoked, d.Cookies)
+	// authAPI: JWT atau personal access token (X-API-Key / Bearer bkp_...)
+	authAPI := auth.WithPATs(d.PATs, authJWT)
+	perm := auth.RequirePermission
+
 	// auth
 	r.Route("/auth", func(ar chi.Router) {
 		ar.Post("/register", uh.Register)
 		ar.Post("/login", uh.Login)
+		ar.Post("/login/2fa", uh.LoginMFA)
+		ar.Post("/refresh", uh.RefreshToken)
+		ar.Post("/logout", uh.Logout)
+		ar.Post("/password/forgot", uh.ForgotPassword)
+		ar.Post("/password/reset", uh.ResetPassword)
+		ar.Post("/verify-email", uh.VerifyEmail)
+		ar.With(authJWT).Post("/verify-email/resend", uh.ResendVerification)
+		if uh.OIDC != nil {
+			ar.Get("/oidc/login", uh.OIDCLogin)
+			ar.Get("/oidc/callback", uh.OIDCCallback)
+		}
+		ar.With(authJWT).Get("/sessions", uh.ListSessions)
+		ar.With(authJWT).Delete("/sessions", uh.DeleteSessions)
+		ar.With(authJWT).Delete("/sessions/{id}", uh.DeleteSession)
+		ar.With(authJWT).Post("/2fa/setup", uh.SetupTOTP)
+		ar.With(authJWT).Post("/2fa/confirm", uh.ConfirmTOTP)
+		ar.With(authJWT).Post("/2fa/disable", uh.DisableTOTP)
+		ar.With(authJWT).Post("/2fa/recovery-codes", uh.RegenerateRecoveryCodes)
 	})
 
-	// books (GET publik)
-	r.Get("/books", bh.List)
-	r.Get("/books/{id}", bh.Detail)
+	// admin
+	r.Route("/admin", func(ar chi.Router) {
+		ar.Use(authAPI, d.Perms.Attach)
+		ar.With(perm(rbac.UsersManage)).Get("/users", uh.ListUsers)
+		ar.With(perm(rbac.UsersManage)).Get("/users/{id}", uh.GetUser)
+		ar.With(perm(rbac.UsersManage)).Delete("/users/{id}", uh.DeleteUser)
+		ar.With(perm(rbac.UsersManage)).Post("/users/{id}/suspend", uh.SuspendUser)
+		ar.With(perm(rbac.UsersManage)).Post("/users/{id}/reactivate", uh.ReactivateUser)
+		ar.With(perm(rbac.UsersManage)).Post("/users/{id}/force-password-reset", uh.ForcePasswordReset)
+		ar.With(perm(rbac.UsersManage)).Post("/users/{id}/revoke-tokens", uh.RevokeUserTokens)
+		ar.With(perm(rbac.UsersManage)).Post("/users/{id}/unlock", uh.UnlockUser)
+		ar.With(perm(rbac.UsersManage)).Get("/roles", uh.ListRoles)
+		ar.With(perm(rbac.RolesManage)).Post("/roles", d.RBAC.CreateRole)
+		ar.With(perm(rbac.RolesManage)).Get("/roles/{role}/permissions", d.RBAC.RolePermissions)
+		ar.With(perm(rbac.RolesManage)).Put("/roles/{role}/permissions", d.RBAC.SetRolePermissions)
+		ar.With(perm(rbac.RolesManage)).Get("/permissions", d.RBAC.ListPermissions)
+		ar.With(perm(rbac.AuditRead)).Get("/audit", d.Audit.HandleList)
+	})
 
-	// books (write: require JWT + roles)
-	sec := auth.MustJWTSecret()
+	// organisasi; di /orgs/{orgID} izin org diambil dari role keanggotaan
+	r.Route("/orgs", func(or chi.Router) {
+		or.Use(authAPI)
+		or.With(auth.DenyPAT).Post("/", oh.Create)
+		or.Get("/", oh.List)
+		or.With(auth.DenyPAT).Post("/invitations/accept", oh.AcceptInvitation)
+		or.Route("/{orgID}", func(sr chi.Router) {
+			sr.Use(oh.Member, d.Perms.Attach)
+			sr.Get("/", oh.Get)
+			sr.With(perm(rbac.OrgsManage)).Delete("/", oh.Delete)
+			sr.Get("/members", oh.Members)
+			sr.With(perm(rbac.OrgsManage)).Put("/members/{userID}", oh.SetMemberRole)
+			sr.Delete("/members/{userID}", oh.RemoveMember)
+			sr.With(perm(rbac.OrgsManage)).Post("/invitations", oh.Invite)
+			sr.With(perm(rbac.OrgsManage)).Get("/invitations", oh.Invitations)
+			sr.With(perm(rbac.OrgsManage)).Delete("/invitations/{id}", oh.RevokeInvitation)
+		})
+	})
+
+	// books: semua butuh JWT, hasil dibatasi ke library pemanggil (books:all: semua)
+	// di tenant aktif (header X-Org-ID, tanpa header = ruang pribadi).
+	// Izin per route; mapping role -> izin ada di DB (lihat rbac).
 	protected := chi.NewRouter()
-	protected.Use(auth.AuthJWT(sec))
-	protected.With(auth.RequireRoles("editor", "admin")).Post("/books", bh.Create)
-	protected.With(auth.RequireRoles("editor", "admin")).Put("/books/{id}", bh.Update)
-	protected.With(auth.RequireRoles("admin")).Delete("/books/{id}", bh.Delete)
+	protected.Use(authAPI, oh.Active, d.Perms.Attach)
+	protected.With(perm(rbac.BooksRead)).Get("/books", bh.List)
+	protected.With(perm(rbac.BooksExport)).Get("/books/export", bh.Export)
+	protected.With(perm(rbac.BooksExport)).Post("/books/export/jobs", bh.CreateExportJob)
+	protected.With(perm(rbac.BooksExport)).Get("/books/export/jobs/{jobId}", bh.ExportJob)
+	protected.With(perm(rbac.BooksExport)).Get("/books/export/jobs/{jobId}/download", bh.DownloadExportJob)
+	protected.With(perm(rbac.BooksExport)).Delete("/books/export/jobs/{jobId}", bh.DeleteExportJob)
+	protected.With(perm(rbac.BooksRead)).Get("/books/{id}", bh.Detail)
+	protected.With(perm(rbac.BooksRead)).Get("/books/{id}/sessions", bh.ListSessions)
+	protected.With(perm(rbac.BooksRead)).Get("/books/{id}/timeline", bh.Timeline)
+
+	// write: kepemilikan dicek di store
+	protected.With(perm(rbac.BooksCreate)).Post("/books", bh.Create)
+	protected.With(perm(rbac.BooksImport)).Post("/books/import", bh.Import)
+	protected.With(perm(rbac.BooksUpdate)).Put("/books/{id}", bh.Update)
+	protected.With(perm(rbac.BooksUpdate)).Patch("/books/{id}", bh.Patch)
+	protected.With(perm(rbac.BooksDelete)).Delete("/books/{id}", bh.Delete)
+	protected.With(perm(rbac.ReadingWrite)).Post("/books/{id}/sessions", bh.CreateSession)
+
+	protected.With(perm(rbac.UsersManage)).Put("/users/{id}/roles", uh.SetRoles)
+
+	// profil sendiri; perubahan hanya dari login interaktif (bukan PAT)
+	protected.Get("/users/me", uh.Me)
+	protected.With(auth.DenyPAT).Patch("/users/me", uh.UpdateMe)
+	protected.With(auth.DenyPAT).Post("/users/me/password", uh.ChangePassword)
+	protected.With(auth.DenyPAT).Delete("/users/me", uh.DeleteMe)
+
+	// personal access token: hanya bisa dikelola dari login interaktif
+	protected.With(auth.DenyPAT).Post("/users/me/tokens", uh.CreateToken)
+	protected.With(auth.DenyPAT).Get("/users/me/tokens", uh.ListTokens)
+	protected.With(auth.DenyPAT).Delete("/users/me/tokens/{id}", uh.DeleteToken)
 	r.Mount("/", protected)
 
 	return r
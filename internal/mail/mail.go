@@ -0,0 +1,135 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+// Message = email teks sederhana.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+type Mailer interface {
+	Send(m Message) error
+}
+
+// FromEnv memilih implementasi dari MAIL_DRIVER:
+//
+//	smtp    SMTP_HOST, SMTP_PORT (587), SMTP_USER, SMTP_PASSWORD, MAIL_FROM
+//	file    tulis .eml ke MAIL_DIR (default: <tmp>/books-mail), utk lokal
+//	memory  simpan di memori (test)
+//
+// Default: file.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	switch d := os.Getenv("MAIL_DRIVER"); d {
+	case "smtp":
+		port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if port == 0 {
+			port = 587
+		}
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST not set")
+		}
+		return NewSMTP(host, port, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "", "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "books-mail")
+		}
+		return NewFile(dir, from)
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", d)
+	}
+}
+
+// format RFC 5322 minimal; header disaring dari CR/LF (header injection)
+func render(from string, m Message) []byte {
+	clean := func(s string) string { return strings.NewReplacer("\r", "", "\n", "").Replace(s) }
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", clean(from))
+	fmt.Fprintf(&b, "To: %s\r\n", clean(m.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", clean(m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Text, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTP: STARTTLS dipakai otomatis jika server mendukung.
+func NewSMTP(host string, port int, user, password, from string) Mailer {
+	var a smtp.Auth
+	if user != "" {
+		a = smtp.PlainAuth("", user, password, host)
+	}
+	return &smtpMailer{addr: net.JoinHostPort(host, strconv.Itoa(port)), auth: a, from: from}
+}
+
+func (s *smtpMailer) Send(m Message) error {
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{m.To}, render(s.from, m))
+}
+
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFile menulis tiap pesan sbg file .eml di dir.
+func NewFile(dir, from string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+func (f *fileMailer) Send(m Message) error {
+	name := time.Now().UTC().Format("20060102-150405") + "-" + util.RandomID() + ".eml"
+	return os.WriteFile(filepath.Join(f.dir, name), render(f.from, m), 0o600)
+}
+
+// Memory menyimpan pesan terkirim; aman dipakai bersamaan.
+type Memory struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+func NewMemory() *Memory { return &Memory{} }
+
+func (m *Memory) Send(msg Message) error {
+	m.mu.Lock()
+	m.msgs = append(m.msgs, msg)
+	m.mu.Unlock()
+	return nil
+}
+
+// Messages: salinan semua pesan, urut kirim.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.msgs...)
+}
@@ -0,0 +1,127 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+// refetchEvery: kid tak dikenal memicu unduh ulang JWKS (rotasi kunci IdP),
+// paling sering sekali per interval ini.
+const refetchEvery = time.Minute
+
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	p.mu.RLock()
+	k, ok := p.lookup(kid)
+	stale := time.Since(p.fetched) > refetchEvery
+	p.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+	if stale {
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		p.mu.RLock()
+		k, ok = p.lookup(kid)
+		p.mu.RUnlock()
+		if ok {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("oidc: unknown key %q", kid)
+}
+
+// lookup: tanpa kid hanya boleh jika IdP punya tepat satu kunci.
+func (p *Provider) lookup(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []auth.JWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.meta.JWKSURI, &set); err != nil {
+		return fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]any{}
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		k, err := parseJWK(j)
+		if err != nil {
+			continue // tipe kunci yang tidak kita pakai
+		}
+		keys[j.Kid] = k
+	}
+	p.mu.Lock()
+	p.keys, p.fetched = keys, time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+func parseJWK(j auth.JWK) (any, error) {
+	dec := base64.RawURLEncoding.DecodeString
+	switch j.Kty {
+	case "RSA":
+		n, err := dec(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := dec(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var crv elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			crv = elliptic.P256()
+		case "P-384":
+			crv = elliptic.P384()
+		case "P-521":
+			crv = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := dec(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := dec(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: crv, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !crv.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("point not on curve")
+		}
+		return pub, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := dec(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("bad Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported kty %q", j.Kty)
+}
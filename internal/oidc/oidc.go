@@ -0,0 +1,337 @@
+// Package oidc: relying party OpenID Connect (authorization code + PKCE)
+// utk login lewat IdP perusahaan.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PendingTTL: batas waktu user menyelesaikan login di IdP.
+const PendingTTL = 10 * time.Minute
+
+var (
+	ErrStateInvalid   = errors.New("oidc: unknown or expired state")
+	ErrIDTokenInvalid = errors.New("oidc: invalid id token")
+)
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // "" = public client (PKCE saja)
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim: klaim ID token berisi grup user (default "groups").
+	GroupsClaim string
+	// GroupRoles: grup IdP -> role API.
+	GroupRoles map[string]string
+	HTTPClient *http.Client
+}
+
+// ConfigFromEnv: ok=false jika OIDC_ISSUER tidak di-set (OIDC nonaktif).
+//
+//	OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL
+//	OIDC_SCOPES        default "openid email profile"
+//	OIDC_GROUPS_CLAIM  default "groups"
+//	OIDC_ROLE_MAP      "grup=role,grup2=role2"
+func ConfigFromEnv() (Config, bool, error) {
+	c := Config{
+		Issuer:       strings.TrimRight(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		GroupRoles:   map[string]string{},
+	}
+	if c.Issuer == "" {
+		return c, false, nil
+	}
+	if c.ClientID == "" || c.RedirectURL == "" {
+		return c, false, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+	for _, kv := range strings.Split(os.Getenv("OIDC_ROLE_MAP"), ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		g, r, ok := strings.Cut(kv, "=")
+		if !ok || g == "" || r == "" {
+			return c, false, fmt.Errorf("OIDC_ROLE_MAP: bad entry %q", kv)
+		}
+		c.GroupRoles[strings.TrimSpace(g)] = strings.TrimSpace(r)
+	}
+	return c, true, nil
+}
+
+// Roles: role hasil pemetaan grup, urut & unik (nil = tidak ada yang cocok).
+func (c Config) Roles(groups []string) []string {
+	var out []string
+	for _, g := range groups {
+		if r, ok := c.GroupRoles[g]; ok {
+			out = append(out, r)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// Claims = isi ID token yang dipakai utk menautkan / membuat user.
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Pending: state login (state, nonce, PKCE verifier) antara AuthURL dan
+// Exchange. Disimpan di cookie browser (lihat Encode), bukan di memori
+// server, jadi callback boleh mendarat di instance mana pun dan state
+// terikat ke browser yang memulai login.
+type Pending struct {
+	State    string
+	Nonce    string
+	Verifier string
+	Exp      time.Time
+}
+
+// Encode: nilai cookie (base64url dipisah titik, aman utk cookie).
+func (pd Pending) Encode() string {
+	return strings.Join([]string{pd.State, pd.Nonce, pd.Verifier, strconv.FormatInt(pd.Exp.Unix(), 10)}, ".")
+}
+
+// DecodePending: kebalikan Encode; ErrStateInvalid jika rusak/kedaluwarsa.
+func DecodePending(s string) (Pending, error) {
+	f := strings.Split(s, ".")
+	if len(f) != 4 || f[0] == "" || f[1] == "" || f[2] == "" {
+		return Pending{}, ErrStateInvalid
+	}
+	exp, err := strconv.ParseInt(f[3], 10, 64)
+	if err != nil || time.Now().After(time.Unix(exp, 0)) {
+		return Pending{}, ErrStateInvalid
+	}
+	return Pending{State: f[0], Nonce: f[1], Verifier: f[2], Exp: time.Unix(exp, 0)}, nil
+}
+
+// Provider = satu IdP hasil discovery.
+type Provider struct {
+	cfg  Config
+	meta metadata
+
+	mu      sync.RWMutex
+	keys    map[string]any
+	fetched time.Time
+}
+
+// NewProvider menjalankan discovery (issuer/.well-known/openid-configuration).
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	p := &Provider{cfg: cfg}
+	if err := p.getJSON(ctx, cfg.Issuer+"/.well-known/openid-configuration", &p.meta); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// OIDC Discovery §4.3: issuer di metadata harus sama persis
+	if p.meta.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q != %q", p.meta.Issuer, cfg.Issuer)
+	}
+	if p.meta.AuthorizationEndpoint == "" || p.meta.TokenEndpoint == "" || p.meta.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete metadata")
+	}
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Provider) Config() Config { return p.cfg }
+
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, res.Status)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
+
+func randString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthURL membuat state/nonce/PKCE baru dan mengembalikan URL redirect ke
+// IdP; pd harus disimpan pemanggil (cookie) sampai callback.
+func (p *Provider) AuthURL() (string, Pending, error) {
+	state, err := randString()
+	if err != nil {
+		return "", Pending{}, err
+	}
+	nonce, err := randString()
+	if err != nil {
+		return "", Pending{}, err
+	}
+	verifier, err := randString()
+	if err != nil {
+		return "", Pending{}, err
+	}
+	pd := Pending{State: state, Nonce: nonce, Verifier: verifier, Exp: time.Now().Add(PendingTTL)}
+
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(p.meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.meta.AuthorizationEndpoint + sep + q.Encode(), pd, nil
+}
+
+// Exchange: state dari callback harus sama dgn pd (milik browser ini);
+// tukar code ke token endpoint lalu validasi ID token.
+func (p *Provider) Exchange(ctx context.Context, pd Pending, state, code string) (*Claims, error) {
+	if pd.State == "" || subtle.ConstantTimeCompare([]byte(pd.State), []byte(state)) != 1 ||
+		time.Now().After(pd.Exp) {
+		return nil, ErrStateInvalid
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", pd.Verifier)
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	res, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var tok struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+		Desc    string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("oidc token endpoint: %s: %w", res.Status, err)
+	}
+	if res.StatusCode != http.StatusOK || tok.IDToken == "" {
+		return nil, fmt.Errorf("oidc token endpoint: %s %s %s", res.Status, tok.Error, tok.Desc)
+	}
+	return p.verify(ctx, tok.IDToken, pd.Nonce)
+}
+
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+func (p *Provider) verify(ctx context.Context, raw, nonce string) (*Claims, error) {
+	mc := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, mc, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods(idTokenMethods),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalid, err)
+	}
+	if n, _ := mc["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrIDTokenInvalid)
+	}
+	// beberapa audience: azp harus client kita (OIDC Core §3.1.3.7)
+	if aud, _ := mc.GetAudience(); len(aud) > 1 {
+		if azp, _ := mc["azp"].(string); azp != p.cfg.ClientID {
+			return nil, fmt.Errorf("%w: azp mismatch", ErrIDTokenInvalid)
+		}
+	}
+	c := &Claims{Issuer: p.cfg.Issuer}
+	c.Subject, _ = mc["sub"].(string)
+	c.Email, _ = mc["email"].(string)
+	c.Name, _ = mc["name"].(string)
+	c.PreferredUsername, _ = mc["preferred_username"].(string)
+	// sebagian IdP mengirim email_verified sebagai string
+	switch v := mc["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = v
+	case string:
+		c.EmailVerified = v == "true"
+	}
+	switch v := mc[p.cfg.GroupsClaim].(type) {
+	case []any:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				c.Groups = append(c.Groups, s)
+			}
+		}
+	case string:
+		c.Groups = strings.Fields(v)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrIDTokenInvalid)
+	}
+	return c, nil
+}
@@ -0,0 +1,143 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer = "https://idp.example.com"
+	testClient = "books-api"
+)
+
+// testProvider: kunci sudah "diunduh" (fetched baru) sehingga kid tak
+// dikenal langsung ditolak tanpa request JWKS.
+func testProvider(t *testing.T) (*Provider, *rsa.PrivateKey) {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Provider{
+		cfg:     Config{Issuer: testIssuer, ClientID: testClient, GroupsClaim: "groups"},
+		keys:    map[string]any{"k1": &k.PublicKey},
+		fetched: time.Now(),
+	}
+	return p, k
+}
+
+func idClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": testIssuer, "aud": testClient, "sub": "user-1", "nonce": "n1",
+		"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+		"email": "a@example.com", "email_verified": "true", "groups": []string{"staff", "ops"},
+	}
+}
+
+func sign(t *testing.T, m jwt.SigningMethod, key any, kid string, c jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(m, c)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	p, k := testProvider(t)
+	c, err := p.verify(context.Background(), sign(t, jwt.SigningMethodRS256, k, "k1", idClaims()), "n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Subject != "user-1" || c.Email != "a@example.com" || !c.EmailVerified || len(c.Groups) != 2 {
+		t.Errorf("claims: %+v", c)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	p, k := testProvider(t)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pubDER, _ := x509.MarshalPKIXPublicKey(&k.PublicKey)
+
+	with := func(f func(jwt.MapClaims)) jwt.MapClaims {
+		c := idClaims()
+		f(c)
+		return c
+	}
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"nonce mismatch", sign(t, jwt.SigningMethodRS256, k, "k1", idClaims()), "n2"},
+		{"nonce missing", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) { delete(c, "nonce") })), "n1"},
+		{"multi aud without azp", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) {
+			c["aud"] = []string{testClient, "other"}
+		})), "n1"},
+		{"multi aud wrong azp", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) {
+			c["aud"], c["azp"] = []string{testClient, "other"}, "other"
+		})), "n1"},
+		{"wrong aud", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) { c["aud"] = "other" })), "n1"},
+		{"wrong iss", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) { c["iss"] = "https://evil" })), "n1"},
+		{"expired", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		})), "n1"},
+		{"no exp", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) { delete(c, "exp") })), "n1"},
+		{"no sub", sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) { delete(c, "sub") })), "n1"},
+		// alg confusion: HS256 dgn public key sebagai secret
+		{"alg HS256", sign(t, jwt.SigningMethodHS256, pubDER, "k1", idClaims()), "n1"},
+		{"alg none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "k1", idClaims()), "n1"},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, k, "k2", idClaims()), "n1"},
+		{"wrong key", sign(t, jwt.SigningMethodES256, other, "k1", idClaims()), "n1"},
+	}
+	for _, tc := range tests {
+		if _, err := p.verify(context.Background(), tc.token, tc.nonce); !errors.Is(err, ErrIDTokenInvalid) {
+			t.Errorf("%s: got %v, want ErrIDTokenInvalid", tc.name, err)
+		}
+	}
+
+	// beberapa aud + azp = client kita: diterima
+	ok := sign(t, jwt.SigningMethodRS256, k, "k1", with(func(c jwt.MapClaims) {
+		c["aud"], c["azp"] = []string{testClient, "other"}, testClient
+	}))
+	if _, err := p.verify(context.Background(), ok, "n1"); err != nil {
+		t.Errorf("multi aud with azp: %v", err)
+	}
+}
+
+func TestPendingCookie(t *testing.T) {
+	pd := Pending{State: "s", Nonce: "n", Verifier: "v", Exp: time.Now().Add(time.Minute).Truncate(time.Second)}
+	got, err := DecodePending(pd.Encode())
+	if err != nil || got != pd {
+		t.Fatalf("round trip: %+v %v", got, err)
+	}
+	pd.Exp = time.Now().Add(-time.Second)
+	for _, s := range []string{pd.Encode(), "", "s.n.v", "s..v.1", "s.n.v.x"} {
+		if _, err := DecodePending(s); err != ErrStateInvalid {
+			t.Errorf("%q: got %v, want ErrStateInvalid", s, err)
+		}
+	}
+	// state callback harus sama dgn cookie
+	p, _ := testProvider(t)
+	pd.Exp = time.Now().Add(time.Minute)
+	if _, err := p.Exchange(context.Background(), pd, "other", "code"); err != ErrStateInvalid {
+		t.Errorf("state mismatch: got %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), Pending{}, "", "code"); err != ErrStateInvalid {
+		t.Errorf("no cookie: got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package orgs
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/mail"
+	"github.com/ImamSR/go-books-api/internal/rbac"
+)
+
+const (
+	// Header: org aktif utk request; tanpa header = ruang pribadi.
+	Header = "X-Org-ID"
+
+	inviteTTL = 7 * 24 * time.Hour
+)
+
+type Handler struct {
+	Store  Store
+	Mailer mail.Mailer
+	// BaseURL: link undangan = BaseURL + /accept-invitation?token=...
+	BaseURL string
+	// DefaultRole: role undangan jika tidak diisi.
+	DefaultRole string
+}
+
+func NewHandler(s Store, m mail.Mailer) *Handler {
+	return &Handler{Store: s, Mailer: m, BaseURL: "http://localhost:8080", DefaultRole: "editor"}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Active: org aktif dari header X-Org-ID (pemanggil harus anggota). Dipasang
+// setelah auth dan sebelum rbac Attach, yang memakai role org utk izin buku.
+func (h *Handler) Active(next http.Handler) http.Handler {
+	return h.activate(next, func(r *http.Request) string { return r.Header.Get(Header) })
+}
+
+// Member: org dari path {orgID}, utk route /orgs/{orgID}/...
+func (h *Handler) Member(next http.Handler) http.Handler {
+	return h.activate(next, func(r *http.Request) string { return chi.URLParam(r, "orgID") })
+}
+
+func (h *Handler) activate(next http.Handler, orgID func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(orgID(r))
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		uid, _ := auth.UserIDFromCtx(r.Context())
+		role, err := h.Store.MemberRole(id, uid)
+		switch err {
+		case nil:
+		case ErrOrgNotFound:
+			writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": err.Error()})
+			return
+		case ErrNotMember:
+			writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": err.Error()})
+			return
+		default:
+			log.Printf("[orgs.activate] error: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithOrg(r.Context(), id, role)))
+	})
+}
+
+// POST /orgs {name}: pembuat jadi admin org.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "name must be 1-100 chars"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	o, err := h.Store.Create(in.Name, uid)
+	if err != nil {
+		log.Printf("[orgs.Create] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	w.Header().Set("Location", "/orgs/"+o.ID)
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "success", "data": map[string]any{"org": o}})
+}
+
+// GET /orgs: org tempat pemanggil jadi anggota.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	list, err := h.Store.ForUser(uid)
+	if err != nil {
+		log.Printf("[orgs.List] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"orgs": list}})
+}
+
+// GET /orgs/{orgID}
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	o, err := h.Store.Get(chi.URLParam(r, "orgID"), uid)
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"org": o}})
+	case ErrOrgNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": err.Error()})
+	default:
+		log.Printf("[orgs.Get] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
+
+// DELETE /orgs/{orgID}: org beserta semua bukunya.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	switch err := h.Store.Delete(chi.URLParam(r, "orgID"), uid); err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "organization deleted"})
+	case ErrOrgNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": err.Error()})
+	default:
+		log.Printf("[orgs.Delete] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
+
+// GET /orgs/{orgID}/members
+func (h *Handler) Members(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Store.Members(chi.URLParam(r, "orgID"))
+	if err != nil {
+		log.Printf("[orgs.Members] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"members": list}})
+}
+
+// memberResult: respons utk perubahan keanggotaan.
+func memberResult(w http.ResponseWriter, op string, err error) {
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success"})
+	case ErrNotMember:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "member not found"})
+	case ErrUnknownRole:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+	case ErrLastAdmin:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": err.Error()})
+	default:
+		log.Printf("[orgs.%s] error: %v", op, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
+
+// PUT /orgs/{orgID}/members/{userID} {role}
+func (h *Handler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Role) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "role required"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	err := h.Store.SetMemberRole(chi.URLParam(r, "orgID"), chi.URLParam(r, "userID"), strings.TrimSpace(in.Role), uid)
+	memberResult(w, "SetMemberRole", err)
+}
+
+// DELETE /orgs/{orgID}/members/{userID}: keluarkan anggota (orgs:manage)
+// atau keluar sendiri.
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	target := chi.URLParam(r, "userID")
+	if target != uid && !auth.HasPermission(r.Context(), rbac.OrgsManage) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "forbidden"})
+		return
+	}
+	memberResult(w, "RemoveMember", h.Store.RemoveMember(chi.URLParam(r, "orgID"), target, uid))
+}
+
+// POST /orgs/{orgID}/invitations {email, role}: undangan via email, berlaku 7 hari.
+func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(in.Email))
+	if at := strings.IndexByte(email, '@'); at < 1 || at == len(email)-1 || strings.ContainsAny(email, " ,<>") {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "valid email required"})
+		return
+	}
+	role := strings.TrimSpace(in.Role)
+	if role == "" {
+		role = h.DefaultRole
+	}
+	orgID := chi.URLParam(r, "orgID")
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	o, err := h.Store.Get(orgID, uid)
+	switch err {
+	case nil:
+	case ErrOrgNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	default:
+		log.Printf("[orgs.Invite] org error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	inv, token, err := h.Store.Invite(orgID, email, role, uid, inviteTTL)
+	switch err {
+	case nil:
+	case ErrAlreadyMember:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	case ErrUnknownRole:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	default:
+		log.Printf("[orgs.Invite] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	m := mail.Message{
+		To:      email,
+		Subject: "You're invited to " + o.Name,
+		Text: "Hi,\n\nYou have been invited to join " + o.Name + " as " + role + ". Accept the invitation here:\n\n" +
+			h.BaseURL + "/accept-invitation?token=" + token +
+			"\n\nThe link expires in 7 days and works once. Sign in with this email address to accept.\n",
+	}
+	go func() {
+		if err := h.Mailer.Send(m); err != nil {
+			log.Printf("[orgs.mail] send to %s failed: %v", m.To, err)
+		}
+	}()
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "success", "data": map[string]any{"invitation": inv}})
+}
+
+// GET /orgs/{orgID}/invitations: undangan yang masih berlaku.
+func (h *Handler) Invitations(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Store.Invitations(chi.URLParam(r, "orgID"))
+	if err != nil {
+		log.Printf("[orgs.Invitations] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"invitations": list}})
+}
+
+// DELETE /orgs/{orgID}/invitations/{id}
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	switch err := h.Store.RevokeInvitation(chi.URLParam(r, "orgID"), chi.URLParam(r, "id"), uid); err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "invitation revoked"})
+	case ErrInvitationNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": err.Error()})
+	default:
+		log.Printf("[orgs.RevokeInvitation] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
+
+// POST /orgs/invitations/accept {token}: pemanggil harus login dgn email
+// yang diundang dan sudah diverifikasi.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "token required"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	o, err := h.Store.Accept(in.Token, uid)
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"org": o}})
+	case ErrInvitationInvalid:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+	case ErrEmailNotVerified:
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": err.Error()})
+	case ErrAlreadyMember:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": err.Error()})
+	default:
+		log.Printf("[orgs.AcceptInvitation] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
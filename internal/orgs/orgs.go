@@ -0,0 +1,439 @@
+package orgs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ImamSR/go-books-api/internal/audit"
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+// AdminRole: pembuat org mendapat role ini; org selalu punya minimal satu.
+const AdminRole = "admin"
+
+var (
+	ErrOrgNotFound        = errors.New("organization not found")
+	ErrNotMember          = errors.New("not a member of this organization")
+	ErrAlreadyMember      = errors.New("already a member of this organization")
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrLastAdmin          = errors.New("organization must keep at least one admin")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationInvalid  = errors.New("invitation is invalid or expired")
+	ErrEmailNotVerified   = errors.New("verify your email address before accepting the invitation")
+)
+
+// Org = organisasi; Role = role pemanggil di org tsb.
+type Org struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	Members   int       `json:"members"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Member struct {
+	UserID   string    `json:"userId"`
+	Email    string    `json:"email"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Invitation = undangan yang belum diterima (token hanya dikirim lewat email).
+type Invitation struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"orgId"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invitedBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Store: perubahan keanggotaan dicatat di audit_log dalam tx yang sama.
+type Store interface {
+	// Create membuat org dgn ownerID sbg anggota ber-role AdminRole.
+	Create(name, ownerID string) (*Org, error)
+	// ForUser: org tempat userID jadi anggota.
+	ForUser(userID string) ([]Org, error)
+	Get(orgID, userID string) (*Org, error)
+	// Delete menghapus org beserta buku-bukunya.
+	Delete(orgID, actorID string) error
+
+	// MemberRole: role userID di org; ErrOrgNotFound jika org tidak ada,
+	// ErrNotMember jika bukan anggota.
+	MemberRole(orgID, userID string) (string, error)
+	Members(orgID string) ([]Member, error)
+	SetMemberRole(orgID, userID, role, actorID string) error
+	RemoveMember(orgID, userID, actorID string) error
+
+	// Invite mengganti undangan lama yang belum diterima utk email yang sama
+	// dan mengembalikan token mentah (utk link email).
+	Invite(orgID, email, role, actorID string, ttl time.Duration) (*Invitation, string, error)
+	Invitations(orgID string) ([]Invitation, error)
+	RevokeInvitation(orgID, id, actorID string) error
+	// Accept: userID harus ber-email sama dgn undangan dan sudah
+	// diverifikasi (ErrEmailNotVerified); token sekali pakai.
+	Accept(token, userID string) (*Org, error)
+}
+
+type pgStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGStore(pool *pgxpool.Pool) Store { return &pgStore{pool: pool} }
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// roleErr: FK role -> roles(name) dilanggar = role tidak terdaftar.
+func roleErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" && strings.Contains(pgErr.ConstraintName, "role") {
+		return ErrUnknownRole
+	}
+	return err
+}
+
+const orgCols = `o.id, o.name, m.role, (SELECT count(*)::int FROM org_members WHERE org_id = o.id), o.created_at`
+
+func (s *pgStore) Create(name, ownerID string) (*Org, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	o := &Org{ID: util.RandomID(), Name: name, Role: AdminRole, Members: 1}
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO organizations (id, name, created_by) VALUES ($1, $2, $3) RETURNING created_at`,
+		o.ID, name, ownerID).Scan(&o.CreatedAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3)`, o.ID, ownerID, AdminRole); err != nil {
+		return nil, err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: ownerID, Action: "org.create", TargetType: "org", TargetID: o.ID,
+		Details: map[string]any{"name": name},
+	}); err != nil {
+		return nil, err
+	}
+	return o, tx.Commit(ctx)
+}
+
+func (s *pgStore) ForUser(userID string) ([]Org, error) {
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT `+orgCols+` FROM org_members m JOIN organizations o ON o.id = m.org_id
+		 WHERE m.user_id = $1 ORDER BY o.name, o.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Org])
+}
+
+func (s *pgStore) Get(orgID, userID string) (*Org, error) {
+	var o Org
+	err := s.pool.QueryRow(context.Background(),
+		`SELECT `+orgCols+` FROM org_members m JOIN organizations o ON o.id = m.org_id
+		 WHERE m.org_id = $1 AND m.user_id = $2`, orgID, userID,
+	).Scan(&o.ID, &o.Name, &o.Role, &o.Members, &o.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (s *pgStore) Delete(orgID, actorID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var name string
+	err = tx.QueryRow(ctx, `DELETE FROM organizations WHERE id = $1 RETURNING name`, orgID).Scan(&name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOrgNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "org.delete", TargetType: "org", TargetID: orgID,
+		Details: map[string]any{"name": name},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *pgStore) MemberRole(orgID, userID string) (string, error) {
+	var role *string
+	err := s.pool.QueryRow(context.Background(),
+		`SELECT m.role FROM organizations o
+		 LEFT JOIN org_members m ON m.org_id = o.id AND m.user_id = $2
+		 WHERE o.id = $1`, orgID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrOrgNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if role == nil {
+		return "", ErrNotMember
+	}
+	return *role, nil
+}
+
+func (s *pgStore) Members(orgID string) ([]Member, error) {
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT u.id, u.email, u.username, m.role, m.joined_at
+		 FROM org_members m JOIN users u ON u.id = m.user_id
+		 WHERE m.org_id = $1 ORDER BY m.joined_at, u.id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Member])
+}
+
+// lockMember: role anggota saat ini, baris dikunci.
+func lockMember(ctx context.Context, tx pgx.Tx, orgID, userID string) (string, error) {
+	var role string
+	err := tx.QueryRow(ctx,
+		`SELECT role FROM org_members WHERE org_id = $1 AND user_id = $2 FOR UPDATE`, orgID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotMember
+	}
+	return role, err
+}
+
+// ensureOtherAdmin: ErrLastAdmin jika userID satu-satunya admin org.
+func ensureOtherAdmin(ctx context.Context, tx pgx.Tx, orgID, userID string) error {
+	var others int
+	err := tx.QueryRow(ctx,
+		`SELECT count(*) FROM (
+			SELECT 1 FROM org_members WHERE org_id = $1 AND user_id <> $2 AND role = $3 FOR UPDATE) a`,
+		orgID, userID, AdminRole).Scan(&others)
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func (s *pgStore) SetMemberRole(orgID, userID, role, actorID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	old, err := lockMember(ctx, tx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if old == role {
+		return nil
+	}
+	if old == AdminRole {
+		if err := ensureOtherAdmin(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE org_members SET role = $3 WHERE org_id = $1 AND user_id = $2`, orgID, userID, role); err != nil {
+		return roleErr(err)
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "org.member.role", TargetType: "org", TargetID: orgID,
+		Details: map[string]any{"userId": userID, "before": old, "after": role},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *pgStore) RemoveMember(orgID, userID, actorID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	role, err := lockMember(ctx, tx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if role == AdminRole {
+		if err := ensureOtherAdmin(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM org_members WHERE org_id = $1 AND user_id = $2`, orgID, userID); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "org.member.remove", TargetType: "org", TargetID: orgID,
+		Details: map[string]any{"userId": userID, "role": role},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *pgStore) Invite(orgID, email, role, actorID string, ttl time.Duration) (*Invitation, string, error) {
+	ctx := context.Background()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback(ctx)
+	var member bool
+	if err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM org_members m JOIN users u ON u.id = m.user_id
+		                WHERE m.org_id = $1 AND lower(u.email) = $2)`, orgID, email).Scan(&member); err != nil {
+		return nil, "", err
+	}
+	if member {
+		return nil, "", ErrAlreadyMember
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM org_invitations WHERE org_id = $1 AND email = $2 AND accepted_at IS NULL`, orgID, email); err != nil {
+		return nil, "", err
+	}
+	inv := &Invitation{ID: util.RandomID(), OrgID: orgID, Email: email, Role: role, InvitedBy: actorID}
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO org_invitations (id, org_id, email, role, token_hash, invited_by, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at, expires_at`,
+		inv.ID, orgID, email, role, hashToken(token), actorID, time.Now().Add(ttl),
+	).Scan(&inv.CreatedAt, &inv.ExpiresAt); err != nil {
+		return nil, "", roleErr(err)
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "org.invitation.create", TargetType: "org", TargetID: orgID,
+		Details: map[string]any{"invitationId": inv.ID, "email": email, "role": role},
+	}); err != nil {
+		return nil, "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", err
+	}
+	return inv, token, nil
+}
+
+func (s *pgStore) Invitations(orgID string) ([]Invitation, error) {
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT id, org_id, email, role, COALESCE(invited_by, ''), created_at, expires_at
+		 FROM org_invitations
+		 WHERE org_id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		 ORDER BY created_at DESC, id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Invitation])
+}
+
+func (s *pgStore) RevokeInvitation(orgID, id, actorID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var email string
+	err = tx.QueryRow(ctx,
+		`DELETE FROM org_invitations WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL RETURNING email`,
+		id, orgID).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvitationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "org.invitation.revoke", TargetType: "org", TargetID: orgID,
+		Details: map[string]any{"invitationId": id, "email": email},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *pgStore) Accept(token, userID string) (*Org, error) {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	var id string
+	var verified bool
+	o := &Org{}
+	err = tx.QueryRow(ctx,
+		`SELECT i.id, o.id, o.name, i.role, o.created_at, u.email_verified_at IS NOT NULL
+		 FROM org_invitations i
+		 JOIN organizations o ON o.id = i.org_id
+		 JOIN users u ON u.id = $2
+		 WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.expires_at > NOW()
+		   AND lower(u.email) = i.email AND u.deleted_at IS NULL
+		 FOR UPDATE OF i`, hashToken(token), userID,
+	).Scan(&id, &o.ID, &o.Name, &o.Role, &o.CreatedAt, &verified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	// email belum terbukti milik pemanggil: siapa pun bisa register dgn email tsb
+	if !verified {
+		return nil, ErrEmailNotVerified
+	}
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		o.ID, userID, o.Role)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrAlreadyMember
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE org_invitations SET accepted_at = NOW(), accepted_by = $2 WHERE id = $1`, id, userID); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow(ctx,
+		`SELECT count(*)::int FROM org_members WHERE org_id = $1`, o.ID).Scan(&o.Members); err != nil {
+		return nil, err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: userID, Action: "org.invitation.accept", TargetType: "org", TargetID: o.ID,
+		Details: map[string]any{"invitationId": id, "role": o.Role},
+	}); err != nil {
+		return nil, err
+	}
+	return o, tx.Commit(ctx)
+}
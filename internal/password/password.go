@@ -0,0 +1,174 @@
+// Package password: hash password (argon2id / bcrypt) dgn format PHC
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash) atau format bcrypt standar
+// ($2a$10$...), plus kebijakan password.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownHash = errors.New("password: unknown hash format")
+
+// Hasher membuat hash baru dgn algoritma/parameter yang dikonfigurasi dan
+// memverifikasi hash format apa pun yang dikenal.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify: ok = cocok; rehash = hash tersimpan memakai algoritma atau
+	// parameter yang berbeda dari konfigurasi (simpan ulang setelah login).
+	Verify(password, encoded string) (ok, rehash bool, err error)
+}
+
+// Argon2id (RFC 9106). Memory dalam KiB.
+type Argon2id struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2id: profil kedua RFC 9106 (64 MiB, t=3).
+var DefaultArgon2id = Argon2id{Memory: 64 * 1024, Time: 3, Threads: 2, SaltLen: 16, KeyLen: 32}
+
+type Bcrypt struct {
+	Cost int
+}
+
+var b64 = base64.RawStdEncoding
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Time, a.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(password, encoded string) (bool, bool, error) {
+	return verify(a, password, encoded)
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(h), err
+}
+
+func (b Bcrypt) Verify(password, encoded string) (bool, bool, error) {
+	return verify(b, password, encoded)
+}
+
+// verify mencocokkan hash format apa pun; rehash jika parameter hash
+// tersimpan != want.
+func verify(want Hasher, password, encoded string) (bool, bool, error) {
+	if encoded == "" { // akun tanpa password lokal (mis. hanya OIDC)
+		return false, false, nil
+	}
+	var (
+		ok   bool
+		used Hasher
+		err  error
+	)
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		ok, used, err = verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		cost, cerr := bcrypt.Cost([]byte(encoded))
+		if cerr != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrUnknownHash, cerr)
+		}
+		used = Bcrypt{Cost: cost}
+		ok = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+	default:
+		return false, false, ErrUnknownHash
+	}
+	if err != nil || !ok {
+		return false, false, err
+	}
+	return true, used != want, nil
+}
+
+func verifyArgon2id(password, encoded string) (bool, Hasher, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, nil, ErrUnknownHash
+	}
+	var v int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &v); err != nil || v != argon2.Version {
+		return false, nil, fmt.Errorf("%w: argon2 version %q", ErrUnknownHash, parts[2])
+	}
+	var a Argon2id
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &a.Memory, &a.Time, &a.Threads); err != nil {
+		return false, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return false, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, nil, fmt.Errorf("%w: bad key", ErrUnknownHash)
+	}
+	a.SaltLen, a.KeyLen = uint32(len(salt)), uint32(len(key))
+	got := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return subtle.ConstantTimeCompare(got, key) == 1, a, nil
+}
+
+// FromEnv:
+//
+//	PASSWORD_HASH     argon2id (default) | bcrypt
+//	ARGON2_MEMORY_KIB default 65536
+//	ARGON2_TIME       default 3
+//	ARGON2_THREADS    default 2
+//	BCRYPT_COST       default 12
+func FromEnv() (Hasher, error) {
+	num := func(key string, def, lo, hi int) (int, error) {
+		v := os.Getenv(key)
+		if v == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("%s must be %d..%d", key, lo, hi)
+		}
+		return n, nil
+	}
+	switch alg := os.Getenv("PASSWORD_HASH"); alg {
+	case "", "argon2id":
+		a := DefaultArgon2id
+		m, err := num("ARGON2_MEMORY_KIB", int(a.Memory), 8*1024, 4*1024*1024)
+		if err != nil {
+			return nil, err
+		}
+		t, err := num("ARGON2_TIME", int(a.Time), 1, 100)
+		if err != nil {
+			return nil, err
+		}
+		p, err := num("ARGON2_THREADS", int(a.Threads), 1, 255)
+		if err != nil {
+			return nil, err
+		}
+		a.Memory, a.Time, a.Threads = uint32(m), uint32(t), uint8(p)
+		return a, nil
+	case "bcrypt":
+		c, err := num("BCRYPT_COST", 12, bcrypt.MinCost, bcrypt.MaxCost)
+		if err != nil {
+			return nil, err
+		}
+		return Bcrypt{Cost: c}, nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASH %q", alg)
+	}
+}
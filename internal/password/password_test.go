@@ -0,0 +1,105 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// parameter kecil agar test cepat; format & alur sama dgn DefaultArgon2id
+var testArgon = Argon2id{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	h, err := testArgon.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(h, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string %q", h)
+	}
+	if ok, rehash, err := testArgon.Verify("correct horse", h); !ok || rehash || err != nil {
+		t.Errorf("verify: ok=%v rehash=%v err=%v", ok, rehash, err)
+	}
+	if ok, _, err := testArgon.Verify("wrong", h); ok || err != nil {
+		t.Errorf("wrong password: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestBcryptRoundTrip(t *testing.T) {
+	b := Bcrypt{Cost: bcrypt.MinCost}
+	h, err := b.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, rehash, err := b.Verify("correct horse", h); !ok || rehash || err != nil {
+		t.Errorf("verify: ok=%v rehash=%v err=%v", ok, rehash, err)
+	}
+	if ok, _, err := b.Verify("wrong", h); ok || err != nil {
+		t.Errorf("wrong password: ok=%v err=%v", ok, err)
+	}
+	// prefix $2y$ (PHP) diterima
+	if ok, _, err := b.Verify("correct horse", "$2y$"+h[4:]); !ok || err != nil {
+		t.Errorf("$2y$: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestRehash(t *testing.T) {
+	argonHash, _ := testArgon.Hash("pw")
+	bcryptHash, _ := Bcrypt{Cost: bcrypt.MinCost}.Hash("pw")
+	stronger := testArgon
+	stronger.Time = 2
+
+	tests := []struct {
+		name   string
+		h      Hasher
+		hash   string
+		rehash bool
+	}{
+		{"same argon2id params", testArgon, argonHash, false},
+		{"argon2id params changed", stronger, argonHash, true},
+		{"bcrypt -> argon2id", testArgon, bcryptHash, true},
+		{"argon2id -> bcrypt", Bcrypt{Cost: bcrypt.MinCost}, argonHash, true},
+		{"bcrypt cost changed", Bcrypt{Cost: bcrypt.MinCost + 1}, bcryptHash, true},
+	}
+	for _, tc := range tests {
+		ok, rehash, err := tc.h.Verify("pw", tc.hash)
+		if !ok || err != nil || rehash != tc.rehash {
+			t.Errorf("%s: ok=%v rehash=%v err=%v, want rehash=%v", tc.name, ok, rehash, err, tc.rehash)
+		}
+	}
+	// password salah tidak pernah minta rehash
+	if ok, rehash, _ := stronger.Verify("other", argonHash); ok || rehash {
+		t.Errorf("wrong password: ok=%v rehash=%v", ok, rehash)
+	}
+}
+
+func TestParsePHC(t *testing.T) {
+	good, _ := testArgon.Hash("pw")
+	parts := strings.Split(good, "$")
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"unknown algorithm", "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA"},
+		{"plain text", "pw"},
+		{"too few fields", "$argon2id$v=19$m=1024,t=1,p=1$" + parts[4]},
+		{"bad version", strings.Replace(good, "v=19", "v=16", 1)},
+		{"bad params", strings.Replace(good, "m=1024,t=1,p=1", "m=x,t=1,p=1", 1)},
+		{"bad salt", strings.Join([]string{"", parts[1], parts[2], parts[3], "!!", parts[5]}, "$")},
+		{"empty key", strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], ""}, "$")},
+		{"bad bcrypt", "$2a$xx$invalid"},
+	}
+	for _, tc := range tests {
+		ok, _, err := testArgon.Verify("pw", tc.encoded)
+		if ok || !errors.Is(err, ErrUnknownHash) {
+			t.Errorf("%s: ok=%v err=%v, want ErrUnknownHash", tc.name, ok, err)
+		}
+	}
+	// akun tanpa password lokal: gagal tanpa error
+	if ok, _, err := testArgon.Verify("pw", ""); ok || err != nil {
+		t.Errorf("empty hash: ok=%v err=%v", ok, err)
+	}
+}
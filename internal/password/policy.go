@@ -0,0 +1,110 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrBreached = errors.New("password appears in a list of breached passwords")
+
+// Policy: aturan password baru (register, reset, ganti password).
+type Policy struct {
+	MinLength int // karakter
+	MaxLength int // karakter; batas atas melindungi dari hash input raksasa
+	// breached: SHA-1 (hex huruf besar) password yang bocor.
+	breached map[string]struct{}
+}
+
+// DefaultPolicy: NIST SP 800-63B (min 8, tanpa aturan komposisi).
+var DefaultPolicy = Policy{MinLength: 8, MaxLength: 128}
+
+// PolicyFromEnv:
+//
+//	PASSWORD_MIN_LENGTH  default 8
+//	PASSWORD_MAX_LENGTH  default 128
+//	PASSWORD_BLOCKLIST   file: satu password per baris, atau SHA-1 hex
+//	                     (format HIBP "HASH:count" juga diterima)
+func PolicyFromEnv() (Policy, error) {
+	p := DefaultPolicy
+	for key, dst := range map[string]*int{"PASSWORD_MIN_LENGTH": &p.MinLength, "PASSWORD_MAX_LENGTH": &p.MaxLength} {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return p, fmt.Errorf("%s must be a positive number", key)
+			}
+			*dst = n
+		}
+	}
+	if p.MinLength > p.MaxLength {
+		return p, errors.New("PASSWORD_MIN_LENGTH > PASSWORD_MAX_LENGTH")
+	}
+	if path := os.Getenv("PASSWORD_BLOCKLIST"); path != "" {
+		if err := p.LoadBlocklist(path); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// LoadBlocklist menambah isi file ke daftar password bocor.
+func (p *Policy) LoadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if p.breached == nil {
+		p.breached = map[string]struct{}{}
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if h, _, ok := strings.Cut(line, ":"); ok && isSHA1Hex(h) {
+			line = h
+		}
+		if isSHA1Hex(line) {
+			p.breached[strings.ToUpper(line)] = struct{}{}
+		} else {
+			p.breached[sha1Hex(line)] = struct{}{}
+		}
+	}
+	return sc.Err()
+}
+
+// Check: nil jika password memenuhi kebijakan. Pesan error aman utk klien.
+func (p Policy) Check(password string) error {
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", p.MaxLength)
+	}
+	if _, ok := p.breached[sha1Hex(password)]; ok {
+		return ErrBreached
+	}
+	return nil
+}
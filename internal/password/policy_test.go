@@ -0,0 +1,57 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	list := strings.Join([]string{
+		"password123",
+		"",
+		sha1Hex("letmein!!"),                   // SHA-1 hex
+		strings.ToLower(sha1Hex("qwertyuiop")), // hex huruf kecil
+		sha1Hex("iloveyou99") + ":12345",       // format HIBP
+		"windows10\r",                          // CRLF
+	}, "\n")
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := DefaultPolicy
+	if err := p.LoadBlocklist(path); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pw       string
+		breached bool
+	}{
+		{"password123", true},
+		{"letmein!!", true},
+		{"qwertyuiop", true},
+		{"iloveyou99", true},
+		{"windows10", true},
+		{"Password123", false},
+		{"a long unique passphrase", false},
+	}
+	for _, tc := range tests {
+		err := p.Check(tc.pw)
+		if got := errors.Is(err, ErrBreached); got != tc.breached {
+			t.Errorf("%q: err=%v, want breached=%v", tc.pw, err, tc.breached)
+		}
+	}
+	if err := p.Check("short"); err == nil || errors.Is(err, ErrBreached) {
+		t.Errorf("short: err=%v, want length error", err)
+	}
+	if err := p.Check(strings.Repeat("é", p.MaxLength+1)); err == nil {
+		t.Error("too long: want error")
+	}
+	// DefaultPolicy tidak ikut berubah
+	if err := DefaultPolicy.Check("password123"); err != nil {
+		t.Errorf("DefaultPolicy: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package rbac
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+type Handler struct {
+	Store    Store
+	Resolver *Resolver
+}
+
+func NewHandler(s Store, res *Resolver) *Handler { return &Handler{Store: s, Resolver: res} }
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// GET /admin/permissions
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Store.Permissions()
+	if err != nil {
+		log.Printf("[rbac.ListPermissions] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"permissions": list}})
+}
+
+// GET /admin/roles/{role}/permissions
+func (h *Handler) RolePermissions(w http.ResponseWriter, r *http.Request) {
+	role := chi.URLParam(r, "role")
+	m, err := h.Store.RolePermissions()
+	if err != nil {
+		log.Printf("[rbac.RolePermissions] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	perms, ok := m[role]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "role not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"role": role, "permissions": perms}})
+}
+
+// PUT /admin/roles/{role}/permissions {permissions: [...]}
+// Mengganti seluruh izin role; berlaku utk token yang sudah terbit.
+func (h *Handler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	perms := make([]string, 0, len(in.Permissions))
+	for _, p := range in.Permissions {
+		if p = strings.TrimSpace(p); p != "" {
+			perms = append(perms, p)
+		}
+	}
+	slices.Sort(perms)
+	perms = slices.Compact(perms)
+
+	role := chi.URLParam(r, "role")
+	actor, _ := auth.UserIDFromCtx(r.Context())
+	old, err := h.Store.SetRolePermissions(role, perms, actor)
+	switch err {
+	case nil:
+	case ErrUnknownRole:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "role not found"})
+		return
+	case ErrUnknownPermission:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "unknown permission"})
+		return
+	case ErrLockout:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	default:
+		log.Printf("[rbac.SetRolePermissions] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if err := h.Resolver.Reload(); err != nil {
+		log.Printf("[rbac.SetRolePermissions] reload error: %v", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   map[string]any{"role": role, "permissions": perms, "previous": old},
+	})
+}
+
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// POST /admin/roles {name, description}: role baru tanpa izin.
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	if !roleName.MatchString(in.Name) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "name must match " + roleName.String()})
+		return
+	}
+	actor, _ := auth.UserIDFromCtx(r.Context())
+	switch err := h.Store.CreateRole(in.Name, strings.TrimSpace(in.Description), actor); err {
+	case nil:
+	case ErrRoleExists:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "role already exists"})
+		return
+	default:
+		log.Printf("[rbac.CreateRole] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if err := h.Resolver.Reload(); err != nil {
+		log.Printf("[rbac.CreateRole] reload error: %v", err)
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "success", "data": map[string]any{"role": in.Name}})
+}
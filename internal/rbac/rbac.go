@@ -0,0 +1,262 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ImamSR/go-books-api/internal/audit"
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+// Nama izin yang dipakai router/handler. Daftar lengkap ada di tabel permissions.
+const (
+	BooksRead    = "books:read"
+	BooksExport  = "books:export"
+	BooksCreate  = "books:create"
+	BooksImport  = "books:import"
+	BooksUpdate  = "books:update"
+	BooksDelete  = "books:delete"
+	BooksAll     = "books:all"
+	ReadingWrite = "reading:write"
+	UsersManage  = "users:manage"
+	RolesManage  = "roles:manage"
+	AuditRead    = "audit:read"
+	OrgsManage   = "orgs:manage"
+)
+
+// DefaultPermissions: dimiliki setiap user login apa pun role-nya (role tanpa
+// mapping / role baru), sama seperti sebelum izin diatur per role.
+var DefaultPermissions = []string{BooksRead}
+
+// OrgScoped: izin yang berlaku per organisasi. Di konteks org aktif izin ini
+// hanya berasal dari role keanggotaan org, bukan dari role global user.
+func OrgScoped(perm string) bool {
+	return strings.HasPrefix(perm, "books:") || perm == ReadingWrite || perm == OrgsManage
+}
+
+// role admin harus selalu bisa mengatur role, supaya tidak ada yang terkunci
+const adminRole = "admin"
+
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrLockout           = errors.New("admin role must keep " + RolesManage)
+)
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Store interface {
+	Permissions() ([]Permission, error)
+	// RolePermissions: role -> izin, utk semua role.
+	RolePermissions() (map[string][]string, error)
+	// SetRolePermissions mengganti izin role (dicatat di audit_log) dan
+	// mengembalikan izin sebelumnya.
+	SetRolePermissions(role string, perms []string, actorID string) ([]string, error)
+	CreateRole(name, description, actorID string) error
+}
+
+type pgStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGStore(pool *pgxpool.Pool) Store { return &pgStore{pool: pool} }
+
+func (s *pgStore) Permissions() ([]Permission, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Permission])
+}
+
+func (s *pgStore) RolePermissions() (map[string][]string, error) {
+	rows, err := s.pool.Query(context.Background(),
+		`SELECT r.name, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		 FROM roles r LEFT JOIN role_permissions rp ON rp.role = r.name
+		 GROUP BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]string{}
+	for rows.Next() {
+		var role string
+		var perms []string
+		if err := rows.Scan(&role, &perms); err != nil {
+			return nil, err
+		}
+		out[role] = perms
+	}
+	return out, rows.Err()
+}
+
+func (s *pgStore) SetRolePermissions(role string, perms []string, actorID string) ([]string, error) {
+	if role == adminRole && !slices.Contains(perms, RolesManage) {
+		return nil, ErrLockout
+	}
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	var old []string
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE((SELECT array_agg(permission ORDER BY permission) FROM role_permissions WHERE role = r.name), '{}')
+		 FROM roles r WHERE r.name = $1 FOR UPDATE`, role).Scan(&old)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnknownRole
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role = $1 AND NOT (permission = ANY($2))`, role, perms); err != nil {
+		return nil, err
+	}
+	for _, p := range perms {
+		_, err := tx.Exec(ctx, `INSERT INTO role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`, role, p)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, ErrUnknownPermission
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "role.permissions.update", TargetType: "role", TargetID: role,
+		Details: map[string]any{"before": old, "after": perms},
+	}); err != nil {
+		return nil, err
+	}
+	return old, tx.Commit(ctx)
+}
+
+func (s *pgStore) CreateRole(name, description, actorID string) error {
+	ctx := context.Background()
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO roles (name, description) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`, name, description)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRoleExists
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "role.create", TargetType: "role", TargetID: name,
+		Details: map[string]any{"description": description},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Resolver memetakan role (dari klaim token) ke izin memakai cache
+// role_permissions. Perubahan mapping berlaku tanpa login ulang: instance
+// ini memuat ulang segera, instance lain pada interval sinkron berikutnya.
+type Resolver struct {
+	store Store
+
+	mu    sync.RWMutex
+	perms map[string][]string
+}
+
+func NewResolver(s Store) *Resolver { return &Resolver{store: s} }
+
+// Start memuat mapping lalu memuat ulang tiap interval sampai ctx selesai.
+func (res *Resolver) Start(ctx context.Context, every time.Duration) error {
+	if err := res.Reload(); err != nil {
+		return err
+	}
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := res.Reload(); err != nil {
+					log.Printf("[rbac.Resolver] reload error: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (res *Resolver) Reload() error {
+	m, err := res.store.RolePermissions()
+	if err != nil {
+		return err
+	}
+	res.mu.Lock()
+	res.perms = m
+	res.mu.Unlock()
+	return nil
+}
+
+// Permissions: gabungan izin dari roles.
+func (res *Resolver) Permissions(roles []string) map[string]bool {
+	res.mu.RLock()
+	defer res.mu.RUnlock()
+	out := map[string]bool{}
+	for _, r := range roles {
+		for _, p := range res.perms[r] {
+			out[p] = true
+		}
+	}
+	return out
+}
+
+// Attach: middleware setelah AuthJWT; menaruh izin pemanggil di context
+// (auth.HasPermission / auth.RequirePermission). Dgn org aktif, izin OrgScoped
+// diambil dari role keanggotaan org. DefaultPermissions selalu ditambahkan.
+// Utk personal access token izin dibatasi ke scope token.
+func (res *Resolver) Attach(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perms := res.Permissions(auth.RolesFromCtx(r.Context()))
+		if role, ok := auth.OrgRoleFromCtx(r.Context()); ok {
+			for p := range perms {
+				if OrgScoped(p) {
+					delete(perms, p)
+				}
+			}
+			for p := range res.Permissions([]string{role}) {
+				if OrgScoped(p) {
+					perms[p] = true
+				}
+			}
+		}
+		for _, p := range DefaultPermissions {
+			perms[p] = true
+		}
+		if scopes, ok := auth.ScopesFromCtx(r.Context()); ok {
+			for p := range perms {
+				if !slices.Contains(scopes, p) {
+					delete(perms, p)
+				}
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPermissions(r.Context(), perms)))
+	})
+}
@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Cipher mengenkripsi secret TOTP sebelum disimpan di DB (AES-256-GCM,
+// hasil = nonce || ciphertext).
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("totp: key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// ErrNoKey: TOTP_ENC_KEY tidak di-set (2FA tidak bisa dipakai).
+var ErrNoKey = errors.New("TOTP_ENC_KEY not set")
+
+// CipherFromEnv: TOTP_ENC_KEY = 32 byte base64, kunci khusus 2FA (tidak
+// diturunkan dari JWT_SECRET). Tanpa itu -> ErrNoKey.
+func CipherFromEnv() (*Cipher, error) {
+	v := os.Getenv("TOTP_ENC_KEY")
+	if v == "" {
+		return nil, ErrNoKey
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP_ENC_KEY: %w", err)
+	}
+	return NewCipher(key)
+}
+
+func (c *Cipher) Seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (c *Cipher) Open(sealed []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("totp: sealed secret too short")
+	}
+	return c.aead.Open(nil, sealed[:n], sealed[n:], nil)
+}
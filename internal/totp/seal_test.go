@@ -0,0 +1,39 @@
+package totp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := c.Seal([]byte(rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := c.Open(sealed); err != nil || string(plain) != rfcSecret {
+		t.Errorf("open: %q %v", plain, err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := c.Open(sealed); err == nil {
+		t.Error("tampered ciphertext: want error")
+	}
+	if _, err := NewCipher(make([]byte, 16)); err == nil {
+		t.Error("16 byte key: want error")
+	}
+}
+
+func TestCipherFromEnv(t *testing.T) {
+	t.Setenv("TOTP_ENC_KEY", "")
+	t.Setenv("JWT_SECRET", "secret")
+	if _, err := CipherFromEnv(); err != ErrNoKey {
+		t.Errorf("no key: got %v, want ErrNoKey (no JWT_SECRET fallback)", err)
+	}
+	t.Setenv("TOTP_ENC_KEY", "AAAA")
+	if _, err := CipherFromEnv(); err == nil {
+		t.Error("short key: want error")
+	}
+}
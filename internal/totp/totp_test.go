@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 lampiran B (SHA1, secret "12345678901234567890"); kode 6 digit
+// = 6 digit terakhir dari kode 8 digit di RFC.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v.code {
+			t.Errorf("t=%d: got %s, want %s", v.unix, got, v.code)
+		}
+	}
+	// secret huruf kecil / spasi diterima, base32 rusak ditolak
+	if got, _ := Code(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", 1); got != "287082" {
+		t.Errorf("lowercase secret: got %s", got)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("bad secret: want error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(s int64) string {
+		c, _ := Code(rfcSecret, s)
+		return c
+	}
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+		step int64
+	}{
+		{"current", code(step), true, step},
+		{"previous step (skew)", code(step - 1), true, step - 1},
+		{"next step (skew)", code(step + 1), true, step + 1},
+		{"two steps old", code(step - 2), false, 0},
+		{"spaces", code(step)[:3] + " " + code(step)[3:], true, step},
+		{"too short", code(step)[:5], false, 0},
+		{"wrong", "000000", false, 0},
+	}
+	for _, tc := range tests {
+		got, ok := Validate(rfcSecret, tc.code, now)
+		if ok != tc.ok || got != tc.step {
+			t.Errorf("%s: got (%d, %v), want (%d, %v)", tc.name, got, ok, tc.step, tc.ok)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	s, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(s, 1); err != nil {
+		t.Fatalf("NewSecret %q not usable: %v", s, err)
+	}
+}
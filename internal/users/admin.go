@@ -0,0 +1,156 @@
+package users
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+// reset paksa dari admin: link berlaku lebih lama dari forgot password
+const forcedResetTTL = 24 * time.Hour
+
+// GET /admin/users?q=&role=&status=active|suspended|deleted|all&limit=&offset=
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := ListFilter{Query: q.Get("q"), Role: q.Get("role"), Status: q.Get("status")}
+	switch f.Status {
+	case "", StatusActive, StatusSuspended, StatusDeleted, StatusAll:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "status must be active, suspended, deleted or all"})
+		return
+	}
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	f.Offset, _ = strconv.Atoi(q.Get("offset"))
+	f.Offset = max(f.Offset, 0)
+	list, total, err := h.Repo.List(f)
+	if err != nil {
+		log.Printf("[users.ListUsers] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   map[string]any{"users": list},
+		"meta":   map[string]any{"limit": f.Limit, "offset": f.Offset, "total": total},
+	})
+}
+
+// GET /admin/users/{id}
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Repo.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"user": u}})
+}
+
+// notSelf: admin tidak boleh suspend / menghapus akunnya sendiri lewat /admin.
+func notSelf(w http.ResponseWriter, r *http.Request) (id, actor string, ok bool) {
+	id = chi.URLParam(r, "id")
+	actor, _ = auth.UserIDFromCtx(r.Context())
+	if id == actor {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "cannot apply this to your own account"})
+		return "", "", false
+	}
+	return id, actor, true
+}
+
+// adminResult menulis respons hasil operasi repo admin.
+func adminResult(w http.ResponseWriter, op string, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case ErrUserNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+	case ErrLastAdmin:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "cannot remove the last active admin"})
+	default:
+		log.Printf("[users.%s] error: %v", op, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+	return false
+}
+
+// POST /admin/users/{id}/suspend {reason}
+// Semua sesi, access token (lewat pencabutan yang dicek AuthJWT) dan PAT
+// user langsung tidak berlaku; login ditolak sampai reactivate.
+func (h *Handler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&in)
+	id, actor, ok := notSelf(w, r)
+	if !ok {
+		return
+	}
+	if !adminResult(w, "SuspendUser", h.Repo.Suspend(id, strings.TrimSpace(in.Reason), actor)) {
+		return
+	}
+	h.revokeAll(id)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "user suspended"})
+}
+
+// POST /admin/users/{id}/reactivate: batalkan suspend / soft delete.
+func (h *Handler) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	actor, _ := auth.UserIDFromCtx(r.Context())
+	if !adminResult(w, "ReactivateUser", h.Repo.Reactivate(chi.URLParam(r, "id"), actor)) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "user reactivated"})
+}
+
+// POST /admin/users/{id}/force-password-reset
+// Password lama tidak bisa dipakai login lagi; user dikirimi link reset.
+func (h *Handler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	actor, _ := auth.UserIDFromCtx(r.Context())
+	if !adminResult(w, "ForcePasswordReset", h.Repo.RequirePasswordReset(id, actor)) {
+		return
+	}
+	h.revokeAll(id)
+	u, err := h.Repo.FindByID(id)
+	if err == nil {
+		err = h.sendPasswordReset(u, "An administrator requires you to set a new password. Open:", forcedResetTTL)
+	}
+	if err != nil {
+		log.Printf("[users.ForcePasswordReset] mail error: %v", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "password reset required, email sent"})
+}
+
+// DELETE /admin/users/{id}?hard=true
+// Default soft delete (bisa di-reactivate); hard = hapus permanen.
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, actor, ok := notSelf(w, r)
+	if !ok {
+		return
+	}
+	hard := r.URL.Query().Get("hard") == "true"
+	var err error
+	if hard {
+		err = h.Repo.Delete(id, actor)
+	} else {
+		err = h.Repo.SoftDelete(id, actor)
+	}
+	if !adminResult(w, "DeleteUser", err) {
+		return
+	}
+	if hard {
+		// sesi & PAT ikut terhapus (FK); access token yang sudah terbit dicabut
+		h.revoke(auth.RevokeUser, id)
+	} else {
+		h.revokeAll(id)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "user deleted", "data": map[string]any{"hard": hard}})
+}
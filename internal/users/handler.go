@@ -2,23 +2,87 @@ package users
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ImamSR/go-books-api/internal/audit"
+	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/mail"
+	"github.com/ImamSR/go-books-api/internal/oidc"
+	"github.com/ImamSR/go-books-api/internal/password"
+	"github.com/ImamSR/go-books-api/internal/totp"
 	"github.com/ImamSR/go-books-api/internal/util"
 )
 
 import "log"
 
 
+// TokenGen membuat access token utk user + sesi (sid).
+type TokenGen func(sub string, roles []string, sid string) (string, error)
+
 type Handler struct {
 	Repo     Repo
-	TokenGen func(sub string, roles []string) (string, error)
+	TokenGen TokenGen
+	Refresh  auth.RefreshStore
+	// Revoked: pencabutan access token (logout, hapus sesi, reuse refresh).
+	Revoked *auth.Revocations
+	// DefaultRole: satu-satunya role yang didapat lewat Register.
+	DefaultRole string
+	// RolePermissions: izin gabungan roles (rbac.Resolver.Permissions). Tanpa
+	// roles:manage, PUT /users/{id}/roles hanya boleh memberi role yang
+	// izinnya dimiliki pemanggil; nil = hanya pemegang roles:manage.
+	RolePermissions func(roles []string) map[string]bool
+	// BootstrapAdminEmail: user dgn email ini jadi admin setelah emailnya
+	// terverifikasi, selama belum ada admin sama sekali.
+	BootstrapAdminEmail string
+
+	// Mailer + BaseURL: email reset password / verifikasi (link = BaseURL + path).
+	Mailer  mail.Mailer
+	BaseURL string
+	// RequireVerifiedEmail: login ditolak (403) sebelum email diverifikasi.
+	RequireVerifiedEmail bool
+
+	// 2FA: MFA = token "mfa pending" antara password dan kode; TOTPKey
+	// mengenkripsi secret (nil = setup 2FA tidak tersedia).
+	MFA        *auth.MFATokens
+	TOTPKey    *totp.Cipher
+	TOTPIssuer string
+
+	// PATs: personal access token (/users/me/tokens).
+	PATs auth.PATStore
+
+	// Brute-force: backoff gagal login per akun (DB) dan per IP (memori).
+	AccountBackoff Backoff
+	IPBackoff      Backoff
+	limiter        *loginLimiter
+	// Audit (opsional): kunci per IP dicatat di audit_log.
+	Audit *audit.Log
+	// OIDC (opsional): login lewat IdP, nil = nonaktif.
+	OIDC *oidc.Provider
+	// Cookies (opsional): sesi browser via cookie HttpOnly + CSRF, dipakai
+	// jika klien mengirim X-Session-Mode: cookie. nil = hanya bearer token.
+	Cookies *auth.Cookies
+
+	// Passwords: hash password baru; hash lama (bcrypt / parameter lama)
+	// tetap bisa login dan di-rehash. Policy: aturan password baru.
+	Passwords password.Hasher
+	Policy    password.Policy
+	dummyOnce sync.Once
+	dummyHash string
 }
 
-func NewHandler(r Repo, gen func(string, []string) (string, error)) *Handler {
-	return &Handler{Repo: r, TokenGen: gen}
+func NewHandler(r Repo, gen TokenGen, rs auth.RefreshStore, rv *auth.Revocations, m mail.Mailer) *Handler {
+	return &Handler{Repo: r, TokenGen: gen, Refresh: rs, Revoked: rv, Mailer: m,
+		DefaultRole: "editor", BaseURL: "http://localhost:8080", TOTPIssuer: "Books API",
+		AccountBackoff: Backoff{Threshold: 5, Base: 30 * time.Second, Max: 15 * time.Minute},
+		IPBackoff:      Backoff{Threshold: 20, Base: time.Minute, Max: 15 * time.Minute},
+		limiter:        newLoginLimiter(),
+		Passwords:      password.DefaultArgon2id,
+		Policy:         password.DefaultPolicy,
+	}
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
@@ -48,33 +112,42 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	uname := strings.TrimSpace(in.Username)
-	if uname == "" {
+	if uname != "" && !usernameRe.MatchString(uname) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "username must be 3-32 chars: letters, digits, . _ -"})
+		return
+	}
+	explicit := uname != ""
+	if !explicit {
 		uname = localPart(in.Email)
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	hash, ok := h.newPassword(w, in.Password)
+	if !ok {
 		return
 	}
-	if len(in.Roles) == 0 {
-		in.Roles = []string{"editor"}
-	}
+	// role tidak pernah diambil dari request; lihat PUT /users/{id}/roles
 	u := &User{
 		ID:       util.RandomID(),
 		Email:    in.Email,
 		Username: uname,              // <-- set
 		Password: string(hash),
-		Roles:    in.Roles,
+		Roles:    []string{h.DefaultRole},
 	}
-	if err := h.Repo.Create(u); err != nil {
+	if err := h.createUser(u, explicit); err != nil {
 		if err == ErrEmailTaken {
 			writeJSON(w, http.StatusConflict, map[string]any{"status":"fail","message":"email already used"})
 			return
 		}
+		if err == ErrUsernameTaken {
+			writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "username already used"})
+			return
+		}
 		log.Printf("[users.Register] create error: %v", err)  // <--- tambahkan ini
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"status":"error"})
 		return
 	}
+	if err := h.sendVerification(u); err != nil {
+		log.Printf("[users.Register] verification email error: %v", err)
+	}
 	writeJSON(w, http.StatusCreated, map[string]any{"status": "success"})
 }
 
@@ -86,22 +159,190 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	in.Email = normalizeEmail(in.Email)
+	ip := sessionInfo(r).IP
 	u, err := h.Repo.FindByEmail(in.Email)
 	if err != nil {
+		// email tak terdaftar: jalur & waktu sama dgn password salah (satu tx
+		// hitungan gagal di DB + satu verifikasi hash)
+		if d := h.loginBlocked(ip, nil); d > 0 {
+			tooManyAttempts(w, d)
+			return
+		}
+		if until, err := h.Repo.EmailLoginFailed(in.Email, h.AccountBackoff); err != nil {
+			log.Printf("[users.Login] record failure error: %v", err)
+		} else if until != nil {
+			tooManyAttempts(w, time.Until(*until).Round(time.Second))
+			return
+		}
+		h.dummyVerify(in.Password)
+		h.loginFailed(ip, "")
 		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid credentials"})
 		return
 	}
-	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(in.Password)) != nil {
+	// saat dikunci password tidak dicek sama sekali
+	if d := h.loginBlocked(ip, u); d > 0 {
+		tooManyAttempts(w, d)
+		return
+	}
+	if !h.verifyPassword(u, in.Password) {
+		h.loginFailed(ip, u.ID)
 		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid credentials"})
 		return
 	}
-	token, err := h.TokenGen(u.ID, u.Roles)
+	switch {
+	case u.DeletedAt != nil:
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid credentials"})
+		return
+	case u.SuspendedAt != nil:
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "account suspended"})
+		return
+	case u.PasswordResetRequired:
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "password reset required, use /auth/password/forgot"})
+		return
+	}
+	if h.RequireVerifiedEmail && u.EmailVerifiedAt == nil {
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "email not verified"})
+		return
+	}
+	// hitungan gagal direset setelah faktor terakhir (kode 2FA salah ikut dihitung)
+	if u.TOTPEnabled {
+		h.mfaChallenge(w, u)
+		return
+	}
+	if err := h.Repo.LoginSucceeded(u.ID); err != nil {
+		log.Printf("[users.Login] reset failures error: %v", err)
+	}
+	g, err := h.Refresh.Issue(u.ID, sessionInfo(r))
+	if err != nil {
+		log.Printf("[users.Login] refresh token error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	h.writeTokens(w, r, u, g, h.wantCookies(r), true)
+}
+
+// wantCookies: token dikirim sbg cookie sesi jika mode cookie aktif dan klien
+// memintanya (atau request ini sendiri sudah memakai cookie sesi).
+func (h *Handler) wantCookies(r *http.Request) bool {
+	return h.Cookies != nil && (strings.EqualFold(r.Header.Get(auth.SessionModeHeader), "cookie") || auth.ViaCookie(r.Context()))
+}
+
+// writeTokens: access token baru utk sesi g + refresh token-nya; dgn cookies
+// token hanya ada di cookie HttpOnly dan body berisi token CSRF (baru jika
+// login, sama seperti sebelumnya jika refresh).
+func (h *Handler) writeTokens(w http.ResponseWriter, r *http.Request, u *User, g auth.RefreshGrant, cookies, login bool) {
+	token, err := h.TokenGen(u.ID, u.Roles, g.SessionID)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
 		return
 	}
+	if cookies {
+		csrf, err := h.Cookies.SetSession(w, r, token, g.Token, login)
+		if err != nil {
+			log.Printf("[users.writeTokens] csrf token error: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"tokenType": "cookie",
+				"expiresIn": int(auth.AccessTTL.Seconds()),
+				"csrfToken": csrf,
+			},
+		})
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status": "success",
-		"data":   map[string]any{"accessToken": token},
+		"data": map[string]any{
+			"accessToken":  token,
+			"tokenType":    "Bearer",
+			"expiresIn":    int(auth.AccessTTL.Seconds()),
+			"refreshToken": g.Token,
+		},
 	})
+}
+
+// refreshInput: refresh token dari body, atau dari cookie sesi jika body
+// kosong (wajib lolos cek CSRF). ok=false: respons error sudah ditulis.
+func (h *Handler) refreshInput(w http.ResponseWriter, r *http.Request) (token string, fromCookie, ok bool) {
+	var in RefreshInput
+	err := json.NewDecoder(r.Body).Decode(&in)
+	if err == nil && in.RefreshToken != "" {
+		return in.RefreshToken, false, true
+	}
+	if h.Cookies != nil && (err == nil || err == io.EOF) {
+		if t := h.Cookies.RefreshToken(r); t != "" {
+			if !h.Cookies.CheckCSRF(r) {
+				writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "csrf token mismatch"})
+				return "", false, false
+			}
+			return t, true, true
+		}
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "refreshToken required"})
+	return "", false, false
+}
+
+// POST /auth/refresh {refreshToken}
+// Refresh token sekali pakai: tiap panggilan mengembalikan pasangan token baru.
+// Mode cookie: body kosong, refresh token dari cookie, token baru ke cookie.
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	token, fromCookie, ok := h.refreshInput(w, r)
+	if !ok {
+		return
+	}
+	g, err := h.Refresh.Rotate(token)
+	switch err {
+	case nil:
+	case auth.ErrRefreshReused:
+		// access token sesi ini ikut dicabut
+		log.Printf("[users.Refresh] reused refresh token, session %s revoked (user %s)", g.SessionID, g.UserID)
+		h.revoke(auth.RevokeSID, g.SessionID)
+		fallthrough
+	case auth.ErrRefreshInvalid:
+		if fromCookie {
+			h.Cookies.Clear(w)
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid refresh token"})
+		return
+	default:
+		log.Printf("[users.Refresh] rotate error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	// role dibaca ulang supaya perubahan role berlaku saat refresh
+	u, err := h.Repo.FindByID(g.UserID)
+	if err != nil || u.blocked() {
+		_, _ = h.Refresh.Revoke(g.Token)
+		if fromCookie {
+			h.Cookies.Clear(w)
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid refresh token"})
+		return
+	}
+	h.writeTokens(w, r, u, g, fromCookie, false)
+}
+
+// POST /auth/logout {refreshToken}: cabut sesi refresh token tsb, termasuk
+// access token yang terbit utk sesi itu. Mode cookie: cookie sesi dihapus.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	token, fromCookie, ok := h.refreshInput(w, r)
+	if !ok {
+		return
+	}
+	sid, err := h.Refresh.Revoke(token)
+	if err != nil {
+		log.Printf("[users.Logout] revoke error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if sid != "" {
+		h.revoke(auth.RevokeSID, sid)
+	}
+	if fromCookie {
+		h.Cookies.Clear(w)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success"})
 }
\ No newline at end of file
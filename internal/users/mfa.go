@@ -0,0 +1,304 @@
+package users
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/totp"
+)
+
+const recoveryCodeCount = 10
+
+var errNoTOTPKey = errors.New("2fa encryption key not configured")
+
+// newRecoveryCodes: kode utk user (format xxxx-xxxx) + hash utk disimpan.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(b))
+		codes = append(codes, c[:4]+"-"+c[4:])
+		hashes = append(hashes, hashToken(c))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(s))
+}
+
+// checkSecondFactor menerima kode TOTP (6 digit, sekali pakai per step)
+// atau recovery code yang belum terpakai.
+func (h *Handler) checkSecondFactor(uid, code string) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" {
+		return false, nil
+	}
+	if len(code) != totp.Digits {
+		return h.Repo.UseRecoveryCode(uid, hashToken(normalizeRecoveryCode(code)))
+	}
+	st, err := h.Repo.TOTPState(uid)
+	if err != nil || !st.Enabled {
+		return false, err
+	}
+	step, ok, err := h.validateTOTP(st, code)
+	if err != nil || !ok || step <= st.LastStep {
+		return false, err
+	}
+	return h.Repo.UseTOTPStep(uid, step)
+}
+
+func (h *Handler) validateTOTP(st TOTPState, code string) (int64, bool, error) {
+	if h.TOTPKey == nil {
+		return 0, false, errNoTOTPKey
+	}
+	secret, err := h.TOTPKey.Open(st.Secret)
+	if err != nil {
+		return 0, false, err
+	}
+	step, ok := totp.Validate(string(secret), code, time.Now())
+	return step, ok, nil
+}
+
+// mfaChallenge: password benar tapi 2FA aktif; access token baru terbit
+// lewat POST /auth/login/2fa.
+func (h *Handler) mfaChallenge(w http.ResponseWriter, u *User) {
+	token, err := h.MFA.Issue(u.ID)
+	if err != nil {
+		log.Printf("[users.Login] mfa token error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"mfaRequired": true,
+			"mfaToken":    token,
+			"expiresIn":   int(auth.MFATTL.Seconds()),
+		},
+	})
+}
+
+// POST /auth/login/2fa {mfaToken, code}: code = TOTP atau recovery code.
+func (h *Handler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.MFAToken == "" || in.Code == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "mfaToken & code required"})
+		return
+	}
+	uid, jti, err := h.MFA.Verify(in.MFAToken)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid or expired mfa token"})
+		return
+	}
+	u, err := h.Repo.FindByID(uid)
+	if err != nil || !u.TOTPEnabled || u.blocked() {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid or expired mfa token"})
+		return
+	}
+	ip := sessionInfo(r).IP
+	if d := h.loginBlocked(ip, u); d > 0 {
+		tooManyAttempts(w, d)
+		return
+	}
+	ok, err := h.checkSecondFactor(u.ID, in.Code)
+	if err != nil {
+		log.Printf("[users.LoginMFA] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if !ok {
+		h.loginFailed(ip, u.ID)
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid code"})
+		return
+	}
+	h.MFA.Done(jti)
+	if err := h.Repo.LoginSucceeded(u.ID); err != nil {
+		log.Printf("[users.LoginMFA] reset failures error: %v", err)
+	}
+	g, err := h.Refresh.Issue(u.ID, sessionInfo(r))
+	if err != nil {
+		log.Printf("[users.LoginMFA] refresh token error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	h.writeTokens(w, r, u, g, h.wantCookies(r), true)
+}
+
+// POST /auth/2fa/setup: secret baru (belum aktif sampai dikonfirmasi).
+func (h *Handler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	if h.TOTPKey == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "fail", "message": "2fa not configured"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if u.TOTPEnabled {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "2fa already enabled"})
+		return
+	}
+	secret, err := totp.NewSecret()
+	var sealed []byte
+	if err == nil {
+		sealed, err = h.TOTPKey.Seal([]byte(secret))
+	}
+	if err == nil {
+		err = h.Repo.SetTOTPSecret(u.ID, sealed)
+	}
+	if err == ErrTOTPEnabled {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "2fa already enabled"})
+		return
+	}
+	if err != nil {
+		log.Printf("[users.SetupTOTP] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data": map[string]any{
+			"secret":     secret,
+			"otpauthUri": totp.URI(h.TOTPIssuer, u.Email, secret),
+		},
+	})
+}
+
+// POST /auth/2fa/confirm {code}: aktifkan 2FA; recovery code hanya
+// ditampilkan sekali di respons ini.
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Code == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "code required"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	st, err := h.Repo.TOTPState(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if st.Enabled {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "2fa already enabled"})
+		return
+	}
+	if st.Secret == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "call /auth/2fa/setup first"})
+		return
+	}
+	step, ok, err := h.validateTOTP(st, in.Code)
+	if err == nil && !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid code"})
+		return
+	}
+	var codes, hashes []string
+	if err == nil {
+		codes, hashes, err = newRecoveryCodes()
+	}
+	if err == nil {
+		err = h.Repo.EnableTOTP(uid, step, hashes)
+	}
+	if err == ErrTOTPEnabled {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "2fa already enabled"})
+		return
+	}
+	if err != nil {
+		log.Printf("[users.ConfirmTOTP] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"recoveryCodes": codes}})
+}
+
+// POST /auth/2fa/disable {password, code}
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Password == "" || in.Code == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "password & code required"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if !u.TOTPEnabled {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "2fa not enabled"})
+		return
+	}
+	if !h.verifyPassword(u, in.Password) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid credentials"})
+		return
+	}
+	ok, err := h.checkSecondFactor(u.ID, in.Code)
+	if err == nil && !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid code"})
+		return
+	}
+	if err == nil {
+		err = h.Repo.DisableTOTP(u.ID)
+	}
+	if err != nil {
+		log.Printf("[users.DisableTOTP] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success"})
+}
+
+// POST /auth/2fa/recovery-codes {code}: ganti semua recovery code.
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Code == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "code required"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	st, err := h.Repo.TOTPState(uid)
+	if err != nil || !st.Enabled {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "2fa not enabled"})
+		return
+	}
+	ok, err := h.checkSecondFactor(uid, in.Code)
+	if err == nil && !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid code"})
+		return
+	}
+	var codes, hashes []string
+	if err == nil {
+		codes, hashes, err = newRecoveryCodes()
+	}
+	if err == nil {
+		err = h.Repo.ReplaceRecoveryCodes(uid, hashes)
+	}
+	if err != nil {
+		log.Printf("[users.RegenerateRecoveryCodes] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"recoveryCodes": codes}})
+}
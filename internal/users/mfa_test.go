@@ -0,0 +1,45 @@
+package users
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, %d hashes", len(codes), len(hashes))
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := map[string]bool{}
+	for i, c := range codes {
+		if !format.MatchString(c) {
+			t.Errorf("code %q: want xxxx-xxxx base32", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+		// hash dihitung dari bentuk ternormalisasi, sama dgn saat dipakai
+		if hashes[i] != hashToken(normalizeRecoveryCode(c)) {
+			t.Errorf("hash of %q does not match normalized code", c)
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"abcd-efgh", "abcdefgh"},
+		{"ABCD-EFGH", "abcdefgh"},
+		{"abcd efgh", "abcdefgh"},
+		{"abcdefgh", "abcdefgh"},
+	}
+	for _, tc := range tests {
+		if got := normalizeRecoveryCode(tc.in); got != tc.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
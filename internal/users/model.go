@@ -6,17 +6,28 @@ type User struct {
     ID        string    `json:"id"`
     Email     string    `json:"email"`
     Username  string    `json:"username"`  // <--- TAMBAH
+    DisplayName string  `json:"displayName"`
+    AvatarURL   string  `json:"avatarUrl"`
     Password  string    `json:"-"`         // hashed
     Roles     []string  `json:"roles"`
+    EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+    TOTPEnabled bool `json:"totpEnabled"`
+    LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+    SuspendedAt   *time.Time `json:"suspendedAt,omitempty"`
+    SuspendReason string     `json:"suspendReason,omitempty"`
+    DeletedAt     *time.Time `json:"deletedAt,omitempty"`
+    PasswordResetRequired bool `json:"passwordResetRequired,omitempty"`
     CreatedAt time.Time `json:"createdAt"`
     UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// blocked: akun di-suspend atau dihapus (soft) tidak boleh login / refresh.
+func (u *User) blocked() bool { return u.SuspendedAt != nil || u.DeletedAt != nil }
+
 type RegisterInput struct {
     Email    string   `json:"email"`
     Username string   `json:"username,omitempty"` // <--- TAMBAH
     Password string   `json:"password"`
-    Roles    []string `json:"roles,omitempty"`
 }
 
 
@@ -24,3 +35,42 @@ type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+type RefreshInput struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Role = entri registry role (tabel roles).
+type Role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Users       int    `json:"users"`
+}
+
+type RolesInput struct {
+	Roles []string `json:"roles"`
+}
+
+// TOTPState: status 2FA user; Secret masih terenkripsi (nil = belum setup).
+type TOTPState struct {
+	Secret   []byte
+	Enabled  bool
+	LastStep int64
+}
+
+// Status akun utk filter admin.
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusDeleted   = "deleted"
+	StatusAll       = "all"
+)
+
+// ListFilter utk daftar user admin; Query dicari di email/username/display name.
+type ListFilter struct {
+	Query  string
+	Role   string
+	Status string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,169 @@
+package users
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ImamSR/go-books-api/internal/oidc"
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+// oidcCookie: state login OIDC (state, nonce, PKCE verifier) di browser
+// yang memulai login. __Host- = Secure, Path=/, tanpa Domain: tidak bisa
+// ditanam subdomain lain. SameSite=Lax agar ikut di redirect dari IdP.
+func (h *Handler) oidcCookie(value string, maxAge int) *http.Cookie {
+	secure := h.Cookies == nil || h.Cookies.Secure
+	name := "books_oidc"
+	if secure {
+		name = "__Host-" + name
+	}
+	return &http.Cookie{Name: name, Value: value, Path: "/", MaxAge: maxAge,
+		Secure: secure, HttpOnly: true, SameSite: http.SameSiteLaxMode}
+}
+
+// GET /auth/oidc/login: redirect ke IdP (authorization code + PKCE).
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	u, pd, err := h.OIDC.AuthURL()
+	if err != nil {
+		log.Printf("[users.OIDCLogin] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	http.SetCookie(w, h.oidcCookie(pd.Encode(), int(oidc.PendingTTL.Seconds())))
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// GET /auth/oidc/callback?code=...&state=...
+// Hasilnya sama dgn /auth/login: access + refresh token API, atau
+// mfaRequired jika user mengaktifkan 2FA lokal.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ck := h.oidcCookie("", 0)
+	var pd oidc.Pending
+	if v, err := r.Cookie(ck.Name); err == nil {
+		pd, _ = oidc.DecodePending(v.Value)
+	}
+	// state sekali pakai
+	ck.MaxAge = -1
+	http.SetCookie(w, ck)
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "identity provider: " + e})
+		return
+	}
+	if q.Get("code") == "" || q.Get("state") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "code & state required"})
+		return
+	}
+	c, err := h.OIDC.Exchange(r.Context(), pd, q.Get("state"), q.Get("code"))
+	if err == oidc.ErrStateInvalid {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "login expired, start again"})
+		return
+	}
+	if err != nil {
+		log.Printf("[users.OIDCCallback] exchange error: %v", err)
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "oidc login failed"})
+		return
+	}
+	if c.Email == "" || !c.EmailVerified {
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "email not verified by identity provider"})
+		return
+	}
+	u, err := h.oidcUser(c)
+	if err != nil {
+		log.Printf("[users.OIDCCallback] user error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if u.blocked() {
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "account suspended"})
+		return
+	}
+	h.syncOIDCRoles(u, c.Groups)
+	// IdP tidak menggantikan 2FA lokal
+	if u.TOTPEnabled {
+		h.mfaChallenge(w, u)
+		return
+	}
+	g, err := h.Refresh.Issue(u.ID, sessionInfo(r))
+	if err != nil {
+		log.Printf("[users.OIDCCallback] refresh token error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	// callback dibuka browser: pakai cookie sesi jika mode cookie aktif
+	h.writeTokens(w, r, u, g, h.Cookies != nil, true)
+}
+
+// oidcUser: user yang tertaut ke identitas IdP; jika belum ada, tautkan
+// akun lokal dgn email (sudah diverifikasi IdP) yang sama atau buat baru.
+func (h *Handler) oidcUser(c *oidc.Claims) (*User, error) {
+	u, err := h.Repo.FindByIdentity(c.Issuer, c.Subject)
+	if err == nil {
+		return u, nil
+	}
+	if err != ErrUserNotFound {
+		return nil, err
+	}
+	email := normalizeEmail(c.Email)
+	u, err = h.Repo.FindByEmail(email)
+	if err == ErrUserNotFound {
+		u, err = h.provisionOIDC(c, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.EmailVerifiedAt == nil {
+		if err := h.Repo.MarkEmailVerified(u.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.Repo.LinkIdentity(u.ID, c.Issuer, c.Subject, email); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// provisionOIDC: user baru tanpa password lokal (login hanya lewat IdP
+// sampai user melakukan reset password).
+func (h *Handler) provisionOIDC(c *oidc.Claims, email string) (*User, error) {
+	roles := h.OIDC.Config().Roles(c.Groups)
+	if len(roles) == 0 {
+		roles = []string{h.DefaultRole}
+	}
+	uname := strings.TrimSpace(c.PreferredUsername)
+	if uname == "" {
+		uname = localPart(email)
+	}
+	u := &User{ID: util.RandomID(), Email: email, Username: uname, Roles: roles}
+	err := h.createUser(u, false)
+	if err == ErrUnknownRole {
+		log.Printf("[users.OIDC] mapped roles %v not in registry, using %s", roles, h.DefaultRole)
+		u.Roles = []string{h.DefaultRole}
+		err = h.createUser(u, false)
+	}
+	if err == ErrEmailTaken { // callback paralel utk email yang sama
+		return h.Repo.FindByEmail(email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[users.OIDC] provisioned user %s (%s)", u.ID, email)
+	return u, nil
+}
+
+// syncOIDCRoles: jika OIDC_ROLE_MAP diisi dan grup user cocok, role user
+// mengikuti IdP di setiap login. Tanpa grup yang cocok role tidak diubah.
+func (h *Handler) syncOIDCRoles(u *User, groups []string) {
+	roles := h.OIDC.Config().Roles(groups)
+	if len(roles) == 0 || slices.Equal(roles, u.Roles) {
+		return
+	}
+	if _, err := h.Repo.SetRoles(u.ID, roles, ""); err != nil {
+		log.Printf("[users.OIDC] sync roles %v for %s: %v", roles, u.ID, err)
+		return
+	}
+	u.Roles = roles
+}
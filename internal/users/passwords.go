@@ -0,0 +1,51 @@
+package users
+
+import (
+	"log"
+	"net/http"
+)
+
+// newPassword: hash password baru setelah lolos Policy. false = respons
+// error sudah ditulis.
+func (h *Handler) newPassword(w http.ResponseWriter, pw string) (string, bool) {
+	if err := h.Policy.Check(pw); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": err.Error()})
+		return "", false
+	}
+	hash, err := h.Passwords.Hash(pw)
+	if err != nil {
+		// mis. bcrypt menolak password > 72 byte
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid password"})
+		return "", false
+	}
+	return hash, true
+}
+
+// verifyPassword mencocokkan password user; hash dgn algoritma/parameter
+// lama disimpan ulang memakai hasher saat ini (rehash transparan).
+func (h *Handler) verifyPassword(u *User, pw string) bool {
+	ok, rehash, err := h.Passwords.Verify(pw, u.Password)
+	if err != nil {
+		log.Printf("[users.verifyPassword] %s: %v", u.ID, err)
+		return false
+	}
+	if ok && rehash {
+		if nh, err := h.Passwords.Hash(pw); err != nil {
+			log.Printf("[users.verifyPassword] rehash %s: %v", u.ID, err)
+		} else if err := h.Repo.RehashPassword(u.ID, u.Password, nh); err != nil {
+			log.Printf("[users.verifyPassword] rehash %s: %v", u.ID, err)
+		} else {
+			u.Password = nh
+		}
+	}
+	return ok
+}
+
+// dummyVerify: dipakai saat email tidak terdaftar supaya waktu respons sama
+// dgn password salah (hash dummy dibuat sekali dgn hasher saat ini).
+func (h *Handler) dummyVerify(pw string) {
+	h.dummyOnce.Do(func() {
+		h.dummyHash, _ = h.Passwords.Hash("not-a-real-password")
+	})
+	_, _, _ = h.Passwords.Verify(pw, h.dummyHash)
+}
@@ -0,0 +1,197 @@
+package users
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ImamSR/go-books-api/internal/audit"
+	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/util"
+)
+
+var usernameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,31}$`)
+
+// createUser: Create dgn username default (dari email) diberi akhiran acak
+// jika sudah dipakai; username pilihan user tetap ErrUsernameTaken.
+func (h *Handler) createUser(u *User, explicitUsername bool) error {
+	base := u.Username
+	for i := 0; ; i++ {
+		err := h.Repo.Create(u)
+		if err != ErrUsernameTaken || explicitUsername || i == 3 {
+			return err
+		}
+		u.Username = base + "-" + strings.ToLower(util.RandomID()[:4])
+	}
+}
+
+// GET /users/me
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"user": u}})
+}
+
+// PATCH /users/me {username?, displayName?, avatarUrl?}; avatarUrl "" = hapus.
+func (h *Handler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Username    *string `json:"username"`
+		DisplayName *string `json:"displayName"`
+		AvatarURL   *string `json:"avatarUrl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if in.Username != nil {
+		v := strings.TrimSpace(*in.Username)
+		if !usernameRe.MatchString(v) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "username must be 3-32 chars: letters, digits, . _ -"})
+			return
+		}
+		u.Username = v
+	}
+	if in.DisplayName != nil {
+		v := strings.TrimSpace(*in.DisplayName)
+		if utf8.RuneCountInString(v) > 100 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "displayName max 100 chars"})
+			return
+		}
+		u.DisplayName = v
+	}
+	if in.AvatarURL != nil {
+		v := strings.TrimSpace(*in.AvatarURL)
+		if v != "" {
+			pu, err := url.Parse(v)
+			if err != nil || (pu.Scheme != "https" && pu.Scheme != "http") || pu.Host == "" || len(v) > 2048 {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "avatarUrl must be an http(s) URL"})
+				return
+			}
+		}
+		u.AvatarURL = v
+	}
+	switch err := h.Repo.Update(u); err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"user": u}})
+	case ErrUsernameTaken:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "username already used"})
+	case ErrUserNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+	default:
+		log.Printf("[users.UpdateMe] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
+
+// checkPassword: password saat ini utk aksi sensitif. Gagal dihitung per IP
+// dan per user terpisah dari hitungan login (key reauth:), jadi pencuri
+// token tidak bisa mengunci login pemilik akun. false = respons sudah ditulis.
+func (h *Handler) checkPassword(w http.ResponseWriter, r *http.Request, u *User, password string) bool {
+	ip, key := sessionInfo(r).IP, "reauth:"+u.ID
+	if d := max(h.loginBlocked(ip, nil), h.limiter.blocked(key)); d > 0 {
+		tooManyAttempts(w, d)
+		return false
+	}
+	if !h.verifyPassword(u, password) {
+		h.loginFailed(ip, "")
+		h.limiter.fail(key, h.AccountBackoff)
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "fail", "message": "invalid current password"})
+		return false
+	}
+	return true
+}
+
+// POST /users/me/password {currentPassword, newPassword}
+// Semua sesi lain diakhiri; respons berisi token baru utk sesi ini.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.CurrentPassword == "" || in.NewPassword == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "currentPassword & newPassword required"})
+		return
+	}
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if !h.checkPassword(w, r, u, in.CurrentPassword) {
+		return
+	}
+	hash, ok := h.newPassword(w, in.NewPassword)
+	if !ok {
+		return
+	}
+	if err := h.Repo.SetPassword(u.ID, string(hash)); err != nil {
+		log.Printf("[users.ChangePassword] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if h.Audit != nil {
+		if err := h.Audit.Record(audit.Entry{ActorID: u.ID, Action: "user.password.change", TargetType: "user", TargetID: u.ID}); err != nil {
+			log.Printf("[users.ChangePassword] audit error: %v", err)
+		}
+	}
+	_ = h.Repo.LoginSucceeded(u.ID)
+	// token baru terbit setelah revokeAll; iat ber-ms sehingga token yang
+	// terbit di detik yang sama tidak ikut dicabut (lihat auth.IsRevoked)
+	h.revokeAll(u.ID)
+	g, err := h.Refresh.Issue(u.ID, sessionInfo(r))
+	if err != nil {
+		log.Printf("[users.ChangePassword] refresh token error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	h.writeTokens(w, r, u, g, h.wantCookies(r), true)
+}
+
+// DELETE /users/me {password}: hapus akun sendiri. Akun tanpa password lokal
+// (hanya OIDC) tidak perlu password.
+func (h *Handler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Password string `json:"password"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&in)
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if u.Password != "" && !h.checkPassword(w, r, u, in.Password) {
+		return
+	}
+	switch err := h.Repo.Delete(u.ID, u.ID); err {
+	case nil:
+	case ErrLastAdmin:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "cannot delete the last admin"})
+		return
+	default:
+		log.Printf("[users.DeleteMe] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	// sesi ikut terhapus (FK); access token yang sudah terbit dicabut
+	h.revoke(auth.RevokeUser, u.ID)
+	if auth.ViaCookie(r.Context()) {
+		h.Cookies.Clear(w)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "account deleted"})
+}
@@ -0,0 +1,197 @@
+package users
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/mail"
+)
+
+const (
+	resetTokenTTL  = time.Hour
+	verifyTokenTTL = 48 * time.Hour
+)
+
+func hours(d time.Duration) string {
+	if n := int(d.Hours()); n != 1 {
+		return strconv.Itoa(n) + " hours"
+	}
+	return "1 hour"
+}
+
+// link di email: BaseURL + path + ?token=...
+func (h *Handler) link(path, token string) string {
+	return h.BaseURL + path + "?token=" + token
+}
+
+// sendAsync: kirim di background supaya waktu respons tidak membocorkan
+// apakah email terdaftar; gagal kirim hanya dicatat.
+func (h *Handler) sendAsync(m mail.Message) {
+	go func() {
+		if err := h.Mailer.Send(m); err != nil {
+			log.Printf("[users.mail] send to %s failed: %v", m.To, err)
+		}
+	}()
+}
+
+func (h *Handler) sendVerification(u *User) error {
+	token, err := h.Repo.CreateToken(u.ID, TokenEmailVerify, verifyTokenTTL)
+	if err != nil {
+		return err
+	}
+	h.sendAsync(mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Text: "Hi " + u.Username + ",\n\nConfirm this email address for your account:\n\n" +
+			h.link("/verify-email", token) + "\n\nThe link expires in " + hours(verifyTokenTTL) + ".\n",
+	})
+	return nil
+}
+
+// sendPasswordReset: token reset baru + email berisi intro lalu link.
+func (h *Handler) sendPasswordReset(u *User, intro string, ttl time.Duration) error {
+	token, err := h.Repo.CreateToken(u.ID, TokenPasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+	h.sendAsync(mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Text: "Hi " + u.Username + ",\n\n" + intro + "\n\n" + h.link("/reset-password", token) +
+			"\n\nThe link expires in " + hours(ttl) + " and works once.\n",
+	})
+	return nil
+}
+
+// POST /auth/password/forgot {email}
+// Selalu 202, terdaftar atau tidak (tidak membocorkan akun).
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Email == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "email required"})
+		return
+	}
+	// lookup, token & email di background: waktu respons sama utk email
+	// terdaftar maupun tidak
+	go h.forgotPassword(normalizeEmail(in.Email))
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":  "success",
+		"message": "if the email is registered, a reset link has been sent",
+	})
+}
+
+func (h *Handler) forgotPassword(email string) {
+	u, err := h.Repo.FindByEmail(email)
+	if err != nil || u.DeletedAt != nil {
+		return
+	}
+	if err := h.sendPasswordReset(u, "Someone asked to reset the password for this account. "+
+		"If it was you, open:", resetTokenTTL); err != nil {
+		log.Printf("[users.ForgotPassword] token error: %v", err)
+	}
+}
+
+// POST /auth/password/reset {token, password}
+// Password baru + semua sesi dan access token lama dicabut.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Token == "" || in.Password == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "token & password required"})
+		return
+	}
+	hash, ok := h.newPassword(w, in.Password)
+	if !ok {
+		return
+	}
+	uid, err := h.Repo.ConsumeToken(TokenPasswordReset, in.Token)
+	if err == ErrTokenInvalid {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid or expired token"})
+		return
+	}
+	if err == nil {
+		err = h.Repo.SetPassword(uid, string(hash))
+	}
+	if err != nil {
+		log.Printf("[users.ResetPassword] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	// link dari inbox = bukti kepemilikan email
+	if err := h.Repo.MarkEmailVerified(uid); err != nil {
+		log.Printf("[users.ResetPassword] verify email error: %v", err)
+	}
+	h.revokeAll(uid)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "password updated"})
+}
+
+// POST /auth/verify-email {token}
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "token required"})
+		return
+	}
+	uid, err := h.Repo.ConsumeToken(TokenEmailVerify, in.Token)
+	if err == ErrTokenInvalid {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid or expired token"})
+		return
+	}
+	if err == nil {
+		err = h.Repo.MarkEmailVerified(uid)
+	}
+	if err != nil {
+		log.Printf("[users.VerifyEmail] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	h.bootstrapAdmin(uid)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "email verified"})
+}
+
+// bootstrapAdmin: BOOTSTRAP_ADMIN_EMAIL baru jadi admin setelah pemilik
+// email membuktikannya lewat link verifikasi.
+func (h *Handler) bootstrapAdmin(uid string) {
+	if h.BootstrapAdminEmail == "" {
+		return
+	}
+	u, err := h.Repo.FindByID(uid)
+	if err != nil || u.Email != normalizeEmail(h.BootstrapAdminEmail) {
+		return
+	}
+	if ok, err := h.Repo.BootstrapAdmin(u.Email); err != nil {
+		log.Printf("[users.VerifyEmail] bootstrap admin error: %v", err)
+	} else if ok {
+		log.Printf("[users.VerifyEmail] %s bootstrapped as first admin", u.ID)
+	}
+}
+
+// POST /auth/verify-email/resend (JWT)
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	u, err := h.Repo.FindByID(uid)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if u.EmailVerifiedAt != nil {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "email already verified"})
+		return
+	}
+	if err := h.sendVerification(u); err != nil {
+		log.Printf("[users.ResendVerification] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "success", "message": "verification email sent"})
+}
@@ -2,21 +2,99 @@ package users
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ImamSR/go-books-api/internal/audit"
+	"github.com/ImamSR/go-books-api/internal/util"
 )
 
 var (
 	ErrEmailTaken = errors.New("email already used")
+	ErrUsernameTaken  = errors.New("username already used")
 	ErrUserNotFound   = errors.New("user not found")
+	ErrUnknownRole    = errors.New("unknown role")
+	ErrLastAdmin      = errors.New("cannot remove the last admin")
+	ErrTokenInvalid   = errors.New("invalid or expired token")
+	ErrTOTPEnabled    = errors.New("two-factor authentication already enabled")
 )
 
 type Repo interface {
 	Create(u *User) error
 	FindByEmail(email string) (*User, error)
+	FindByID(id string) (*User, error)
+	// Update menyimpan profil (username, display name, avatar).
+	Update(u *User) error
+	// Delete menghapus user beserta sesi/token dan buku pribadinya (buku org
+	// tetap milik org); admin aktif terakhir tidak bisa dihapus.
+	Delete(id, actorID string) error
+
+	// Admin. Semua perubahan dicatat di audit_log atas nama actorID.
+	List(f ListFilter) (list []User, total int, err error)
+	Suspend(id, reason, actorID string) error
+	// Reactivate membatalkan suspend maupun soft delete.
+	Reactivate(id, actorID string) error
+	SoftDelete(id, actorID string) error
+	// RequirePasswordReset: login ditolak sampai password direset (SetPassword).
+	RequirePasswordReset(id, actorID string) error
+	// SetRoles mengganti role user (dicatat di audit_log atas nama actorID)
+	// dan mengembalikan role sebelumnya.
+	SetRoles(id string, roles []string, actorID string) ([]string, error)
+	ListRoles() ([]Role, error)
+	// BootstrapAdmin menjadikan user dgn email tsb admin, hanya jika belum
+	// ada admin sama sekali dan emailnya sudah terverifikasi. ok=false jika
+	// sudah ada admin / user tidak ada / email belum terverifikasi.
+	BootstrapAdmin(email string) (ok bool, err error)
+
+	// CreateToken membuat token sekali pakai (purpose: TokenPasswordReset |
+	// TokenEmailVerify); token lama yang belum dipakai utk purpose sama dibatalkan.
+	CreateToken(userID, purpose string, ttl time.Duration) (string, error)
+	// ConsumeToken menandai token terpakai dan mengembalikan pemiliknya.
+	ConsumeToken(purpose, token string) (userID string, err error)
+	SetPassword(id, hash string) error
+	// RehashPassword mengganti hash hanya jika hash tersimpan masih oldHash.
+	RehashPassword(id, oldHash, newHash string) error
+	MarkEmailVerified(id string) error
+
+	// 2FA (TOTP). Secret disimpan sudah terenkripsi; recovery code sebagai hash.
+	// SetTOTPSecret menyimpan secret baru yang belum aktif (setup ulang boleh).
+	SetTOTPSecret(id string, sealed []byte) error
+	TOTPState(id string) (TOTPState, error)
+	// EnableTOTP mengaktifkan 2FA + recovery code baru (step = kode konfirmasi).
+	EnableTOTP(id string, step int64, codeHashes []string) error
+	DisableTOTP(id string) error
+	// TOTPInUse: ada user dgn 2FA aktif (butuh TOTP_ENC_KEY saat start).
+	TOTPInUse() (bool, error)
+	// UseTOTPStep: false jika step <= step terakhir (kode sudah pernah dipakai).
+	UseTOTPStep(id string, step int64) (bool, error)
+	ReplaceRecoveryCodes(id string, codeHashes []string) error
+	// UseRecoveryCode: false jika kode tidak ada / sudah terpakai.
+	UseRecoveryCode(id, codeHash string) (bool, error)
+
+	// LoginFailed menambah hitungan gagal login dan mengunci akun sesuai b
+	// (kunci dicatat di audit_log). lockedUntil nil = belum dikunci.
+	LoginFailed(id, ip string, b Backoff) (lockedUntil *time.Time, err error)
+	LoginSucceeded(id string) error
+	// EmailLoginFailed: sama dgn LoginFailed utk email tak terdaftar. Jika
+	// email sedang dikunci kegagalan tidak dihitung dan lockedUntil != nil.
+	EmailLoginFailed(email string, b Backoff) (lockedUntil *time.Time, err error)
+	// Unlock: admin membuka kunci akun + reset hitungan gagal.
+	Unlock(id, actorID string) error
+
+	// Identitas OIDC (issuer + sub). FindByIdentity juga mencatat last_login_at.
+	FindByIdentity(issuer, subject string) (*User, error)
+	LinkIdentity(userID, issuer, subject, email string) error
 }
 
 type pgRepo struct {
@@ -28,30 +106,724 @@ func NewPGRepo(pool *pgxpool.Pool) Repo {
 }
 
 func (r *pgRepo) Create (u *User) error {
+	ctx := context.Background()
 	now := time.Now()
 	u.CreatedAt, u.UpdatedAt = now, now
-	_, err := r.pool.Exec(context.Background(),
-		`INSERT INTO users (id, email, username, password, roles, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		u.ID, u.Email, u.Username, u.Password, u.Roles, u.CreatedAt, u.UpdatedAt,
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx,
+		`INSERT INTO users (id, email, username, password, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		u.ID, u.Email, u.Username, u.Password, u.CreatedAt, u.UpdatedAt,
 	)
 	if err != nil {
-		if strings.Contains(strings.ToLower(err.Error()),"unique") {
-			return ErrEmailTaken
-		}
+		return uniqueErr(err)
+	}
+	if err := insertRoles(ctx, tx, u.ID, u.Roles, ""); err != nil {
 		return err
 	}
+	return tx.Commit(ctx)
+}
+
+// uniqueErr: pelanggaran unique email / username jadi error masing-masing.
+func uniqueErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if strings.Contains(pgErr.ConstraintName, "username") {
+			return ErrUsernameTaken
+		}
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// insertRoles: FK ke roles(name) menolak nama yang tidak terdaftar.
+func insertRoles(ctx context.Context, tx pgx.Tx, userID string, roles []string, grantedBy string) error {
+	var by *string
+	if grantedBy != "" {
+		by = &grantedBy
+	}
+	for _, role := range roles {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO user_roles (user_id, role, granted_by) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			userID, role, by)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrUnknownRole
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// userCols: roles dari user_roles, urut nama.
+const userCols = `u.id, u.email, u.username, u.display_name, u.avatar_url, u.password,
+		COALESCE((SELECT array_agg(ur.role ORDER BY ur.role) FROM user_roles ur WHERE ur.user_id = u.id), '{}'),
+		u.email_verified_at, u.totp_enabled_at IS NOT NULL, u.locked_until,
+		u.suspended_at, u.suspend_reason, u.deleted_at, u.password_reset_required, u.created_at, u.updated_at`
+
+// scanUser: satu baris hasil SELECT userCols.
+func scanUser(row pgx.Row) (*User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Email, &u.Username, &u.DisplayName, &u.AvatarURL, &u.Password, &u.Roles,
+		&u.EmailVerifiedAt, &u.TOTPEnabled, &u.LockedUntil,
+		&u.SuspendedAt, &u.SuspendReason, &u.DeletedAt, &u.PasswordResetRequired, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
 func (r *pgRepo) FindByEmail(email string) (*User, error) {
 	row := r.pool.QueryRow(context.Background(),
-		`SELECT id, email, username, password, roles, created_at, updated_at
-		FROM users WHERE lower(email)=lower($1)`, email)
+		`SELECT `+userCols+`
+		FROM users u WHERE lower(u.email)=lower($1)`, email)
 
-	var u User
-	if err := row.Scan(&u.ID, &u.Email, &u.Username, &u.Password, &u.Roles, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	u, err := scanUser(row)
+	if err != nil {
 		return nil, ErrUserNotFound
 	}
-	return &u, nil
-}
\ No newline at end of file
+	return u, nil
+}
+
+func (r *pgRepo) FindByID(id string) (*User, error) {
+	row := r.pool.QueryRow(context.Background(),
+		`SELECT `+userCols+`
+		FROM users u WHERE u.id=$1`, id)
+
+	u, err := scanUser(row)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (r *pgRepo) SetRoles(id string, roles []string, actorID string) ([]string, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	// kunci baris user supaya perubahan role user yang sama berurutan
+	var old []string
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE((SELECT array_agg(role ORDER BY role) FROM user_roles WHERE user_id = u.id), '{}')
+		FROM users u WHERE u.id = $1 FOR UPDATE`, id).Scan(&old)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// cek admin terakhir sama dgn suspend/hapus: admin suspended/dihapus tidak dihitung
+	if slices.Contains(old, "admin") && !slices.Contains(roles, "admin") {
+		if err := ensureOtherAdmin(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND NOT (role = ANY($2))`, id, roles); err != nil {
+		return nil, err
+	}
+	if err := insertRoles(ctx, tx, id, roles, actorID); err != nil {
+		return nil, err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "user.roles.update", TargetType: "user", TargetID: id,
+		Details: map[string]any{"before": old, "after": roles},
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return old, tx.Commit(ctx)
+}
+
+func (r *pgRepo) ListRoles() ([]Role, error) {
+	rows, err := r.pool.Query(context.Background(),
+		`SELECT r.name, r.description, (SELECT count(*) FROM user_roles ur WHERE ur.role = r.name)
+		FROM roles r ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Role
+	for rows.Next() {
+		var ro Role
+		if err := rows.Scan(&ro.Name, &ro.Description, &ro.Users); err != nil {
+			return nil, err
+		}
+		out = append(out, ro)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgRepo) BootstrapAdmin(email string) (bool, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	// serialisasi antar instance/request: hanya satu yang boleh jadi admin pertama
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('users.bootstrap_admin'))`); err != nil {
+		return false, err
+	}
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM user_roles WHERE role = 'admin')`).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	var id string
+	// hanya email yang sudah terverifikasi (bukti kepemilikan)
+	err = tx.QueryRow(ctx,
+		`SELECT id FROM users
+		 WHERE lower(email) = lower($1) AND email_verified_at IS NOT NULL
+		   AND deleted_at IS NULL AND suspended_at IS NULL`, email).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := insertRoles(ctx, tx, id, []string{"admin"}, ""); err != nil {
+		return false, err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		Action: "user.roles.bootstrap_admin", TargetType: "user", TargetID: id,
+		Details: map[string]any{"email": email},
+	}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+const (
+	TokenPasswordReset = "password_reset"
+	TokenEmailVerify   = "email_verify"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *pgRepo) CreateToken(userID, purpose string, ttl time.Duration) (string, error) {
+	ctx := context.Background()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	// token sebelumnya utk purpose yg sama tidak berlaku lagi
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND (used_at IS NULL OR expires_at < NOW())`,
+		userID, purpose); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO user_tokens (id, user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		util.RandomID(), userID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, tx.Commit(ctx)
+}
+
+func (r *pgRepo) ConsumeToken(purpose, token string) (string, error) {
+	var uid string
+	err := r.pool.QueryRow(context.Background(),
+		`UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, hashToken(token), purpose).Scan(&uid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrTokenInvalid
+	}
+	return uid, err
+}
+
+func (r *pgRepo) SetPassword(id, hash string) error {
+	tag, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET password = $2, password_reset_required = FALSE, updated_at = NOW() WHERE id = $1`, id, hash)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func (r *pgRepo) RehashPassword(id, oldHash, newHash string) error {
+	_, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET password = $3 WHERE id = $1 AND password = $2`, id, oldHash, newHash)
+	return err
+}
+
+func (r *pgRepo) MarkEmailVerified(id string) error {
+	_, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = $1 AND email_verified_at IS NULL`, id)
+	return err
+}
+
+func (r *pgRepo) SetTOTPSecret(id string, sealed []byte) error {
+	tag, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET totp_secret = $2, updated_at = NOW() WHERE id = $1 AND totp_enabled_at IS NULL`, id, sealed)
+	if err == nil && tag.RowsAffected() == 0 {
+		if _, err := r.FindByID(id); err != nil {
+			return err
+		}
+		return ErrTOTPEnabled
+	}
+	return err
+}
+
+func (r *pgRepo) TOTPState(id string) (TOTPState, error) {
+	var st TOTPState
+	err := r.pool.QueryRow(context.Background(),
+		`SELECT totp_secret, totp_enabled_at IS NOT NULL, totp_last_step FROM users WHERE id = $1`, id,
+	).Scan(&st.Secret, &st.Enabled, &st.LastStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return st, ErrUserNotFound
+	}
+	return st, err
+}
+
+func (r *pgRepo) EnableTOTP(id string, step int64, codeHashes []string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx,
+		`UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`, id, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, tx, id, codeHashes); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: id, Action: "user.2fa.enable", TargetType: "user", TargetID: id,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) TOTPInUse() (bool, error) {
+	var ok bool
+	err := r.pool.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM users WHERE totp_enabled_at IS NOT NULL)`).Scan(&ok)
+	return ok, err
+}
+
+func (r *pgRepo) DisableTOTP(id string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx,
+		`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+		WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: id, Action: "user.2fa.disable", TargetType: "user", TargetID: id,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) UseTOTPStep(id string, step int64) (bool, error) {
+	tag, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`, id, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgRepo) ReplaceRecoveryCodes(id string, codeHashes []string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := replaceRecoveryCodes(ctx, tx, id, codeHashes); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: id, Action: "user.2fa.recovery_codes.regenerate", TargetType: "user", TargetID: id,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, id string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO user_recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])`, id, codeHashes)
+	return err
+}
+
+func (r *pgRepo) UseRecoveryCode(id, codeHash string) (bool, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx,
+		`UPDATE user_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		id, codeHash)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: id, Action: "user.2fa.recovery_code.use", TargetType: "user", TargetID: id,
+	}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *pgRepo) LoginFailed(id, ip string, b Backoff) (*time.Time, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	var n int
+	var last *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT failed_logins, last_failed_login_at FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&n, &last)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if last == nil || now.Sub(*last) > failureWindow {
+		n = 0
+	}
+	n++
+	var until *time.Time
+	if d := b.Delay(n); d > 0 {
+		t := now.Add(d)
+		until = &t
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE users SET failed_logins = $2, last_failed_login_at = $3, locked_until = $4 WHERE id = $1`,
+		id, n, now, until); err != nil {
+		return nil, err
+	}
+	if until != nil {
+		if err := audit.Record(ctx, tx, audit.Entry{
+			Action: "user.login.locked", TargetType: "user", TargetID: id,
+			Details: map[string]any{"failures": n, "lockedUntil": until, "ip": ip},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return until, tx.Commit(ctx)
+}
+
+func (r *pgRepo) EmailLoginFailed(email string, b Backoff) (*time.Time, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	key, now := hashToken(email), time.Now()
+	var n int
+	var last time.Time
+	var until *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT failures, last_failed_at, locked_until FROM login_failures WHERE email_hash = $1 FOR UPDATE`, key,
+	).Scan(&n, &last, &until)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// baris baru: sekalian buang hitungan yang sudah kedaluwarsa
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM login_failures WHERE last_failed_at < $1`, now.Add(-failureWindow)); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case until != nil && now.Before(*until):
+		return until, nil
+	case now.Sub(last) > failureWindow:
+		n = 0
+	}
+	n++
+	until = nil
+	if d := b.Delay(n); d > 0 {
+		t := now.Add(d)
+		until = &t
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO login_failures (email_hash, failures, last_failed_at, locked_until) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (email_hash) DO UPDATE SET failures = $2, last_failed_at = $3, locked_until = $4`,
+		key, n, now, until); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit(ctx)
+}
+
+func (r *pgRepo) LoginSucceeded(id string) error {
+	_, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET failed_logins = 0, last_failed_login_at = NULL, locked_until = NULL
+		WHERE id = $1 AND (failed_logins > 0 OR locked_until IS NOT NULL)`, id)
+	return err
+}
+
+func (r *pgRepo) Unlock(id, actorID string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var failures int
+	var until *time.Time
+	err = tx.QueryRow(ctx,
+		`SELECT failed_logins, locked_until FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&failures, &until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE users SET failed_logins = 0, last_failed_login_at = NULL, locked_until = NULL WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: "user.login.unlock", TargetType: "user", TargetID: id,
+		Details: map[string]any{"failures": failures, "lockedUntil": until},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) FindByIdentity(issuer, subject string) (*User, error) {
+	var id string
+	err := r.pool.QueryRow(context.Background(),
+		`UPDATE user_identities SET last_login_at = NOW() WHERE issuer = $1 AND subject = $2 RETURNING user_id`,
+		issuer, subject).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(id)
+}
+
+func (r *pgRepo) LinkIdentity(userID, issuer, subject, email string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO user_identities (issuer, subject, user_id, email) VALUES ($1, $2, $3, $4)`,
+		issuer, subject, userID, email); err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, tx, audit.Entry{
+		Action: "user.identity.link", TargetType: "user", TargetID: userID,
+		Details: map[string]any{"issuer": issuer, "subject": subject, "email": email},
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgRepo) Update(u *User) error {
+	u.UpdatedAt = time.Now()
+	tag, err := r.pool.Exec(context.Background(),
+		`UPDATE users SET username = $2, display_name = $3, avatar_url = $4, updated_at = $5 WHERE id = $1`,
+		u.ID, u.Username, u.DisplayName, u.AvatarURL, u.UpdatedAt)
+	if err != nil {
+		return uniqueErr(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// lockUser mengunci baris user di tx; admin = user punya role admin.
+func lockUser(ctx context.Context, tx pgx.Tx, id string) (email string, admin bool, err error) {
+	err = tx.QueryRow(ctx,
+		`SELECT email, EXISTS (SELECT 1 FROM user_roles WHERE user_id = u.id AND role = 'admin')
+		FROM users u WHERE id = $1 FOR UPDATE`, id).Scan(&email, &admin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrUserNotFound
+	}
+	return email, admin, err
+}
+
+// ensureOtherAdmin: ErrLastAdmin jika tidak ada admin aktif selain id.
+// Baris admin dikunci supaya dua operasi paralel tidak menghabiskan admin.
+func ensureOtherAdmin(ctx context.Context, tx pgx.Tx, id string) error {
+	var others int
+	err := tx.QueryRow(ctx,
+		`SELECT count(*) FROM (
+			SELECT 1 FROM users u JOIN user_roles ur ON ur.user_id = u.id AND ur.role = 'admin'
+			WHERE u.id <> $1 AND u.suspended_at IS NULL AND u.deleted_at IS NULL
+			FOR UPDATE OF u) a`, id).Scan(&others)
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// adminUpdate: kerangka perubahan status user oleh admin (lock, cek admin
+// terakhir jika guardAdmin, update, audit) dalam satu tx.
+func (r *pgRepo) adminUpdate(id, actorID, action string, guardAdmin bool, details map[string]any, sql string, args ...any) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	email, admin, err := lockUser(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if guardAdmin && admin {
+		if err := ensureOtherAdmin(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	if sql != "" {
+		if _, err := tx.Exec(ctx, sql, append([]any{id}, args...)...); err != nil {
+			return err
+		}
+	}
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["email"] = email
+	if err := audit.Record(ctx, tx, audit.Entry{
+		ActorID: actorID, Action: action, TargetType: "user", TargetID: id, Details: details,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// books.owner_id ON DELETE SET NULL: buku pribadi dihapus eksplisit agar
+// tidak tersisa tanpa pemilik.
+func (r *pgRepo) Delete(id, actorID string) error {
+	return r.adminUpdate(id, actorID, "user.delete", true, nil,
+		`WITH b AS (DELETE FROM books WHERE owner_id = $1 AND org_id IS NULL)
+		DELETE FROM users WHERE id = $1`)
+}
+
+func (r *pgRepo) Suspend(id, reason, actorID string) error {
+	return r.adminUpdate(id, actorID, "user.suspend", true, map[string]any{"reason": reason},
+		`UPDATE users SET suspended_at = COALESCE(suspended_at, NOW()), suspend_reason = $2, updated_at = NOW() WHERE id = $1`, reason)
+}
+
+func (r *pgRepo) Reactivate(id, actorID string) error {
+	return r.adminUpdate(id, actorID, "user.reactivate", false, nil,
+		`UPDATE users SET suspended_at = NULL, suspend_reason = '', deleted_at = NULL, updated_at = NOW() WHERE id = $1`)
+}
+
+func (r *pgRepo) SoftDelete(id, actorID string) error {
+	return r.adminUpdate(id, actorID, "user.soft_delete", true, nil,
+		`UPDATE users SET deleted_at = COALESCE(deleted_at, NOW()), updated_at = NOW() WHERE id = $1`)
+}
+
+func (r *pgRepo) RequirePasswordReset(id, actorID string) error {
+	return r.adminUpdate(id, actorID, "user.password.reset_required", false, nil,
+		`UPDATE users SET password_reset_required = TRUE, updated_at = NOW() WHERE id = $1`)
+}
+
+func (r *pgRepo) List(f ListFilter) ([]User, int, error) {
+	where, args := []string{"TRUE"}, []any{}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if q := strings.TrimSpace(f.Query); q != "" {
+		add(`(u.email ILIKE ? OR u.username ILIKE ? OR u.display_name ILIKE ?)`, "%"+likeEscape(q)+"%")
+	}
+	if f.Role != "" {
+		add(`EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.role = ?)`, f.Role)
+	}
+	switch f.Status {
+	case StatusActive:
+		where = append(where, "u.suspended_at IS NULL AND u.deleted_at IS NULL")
+	case StatusSuspended:
+		where = append(where, "u.suspended_at IS NOT NULL AND u.deleted_at IS NULL")
+	case StatusDeleted:
+		where = append(where, "u.deleted_at IS NOT NULL")
+	case StatusAll:
+	default: // tanpa filter: semua kecuali yang dihapus
+		where = append(where, "u.deleted_at IS NULL")
+	}
+	cond := strings.Join(where, " AND ")
+	ctx := context.Background()
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT count(*) FROM users u WHERE `+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	args = append(args, f.Limit, f.Offset)
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+userCols+` FROM users u WHERE `+cond+`
+		ORDER BY u.created_at DESC, u.id LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *u)
+	}
+	return list, total, rows.Err()
+}
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
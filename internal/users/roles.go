@@ -0,0 +1,112 @@
+package users
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+const rolesManage = "roles:manage"
+
+// PUT /users/{id}/roles {roles: [...]} (users:manage)
+// Mengganti seluruh role user; nama harus terdaftar di registry (GET /admin/roles).
+func (h *Handler) SetRoles(w http.ResponseWriter, r *http.Request) {
+	var in RolesInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	roles := make([]string, 0, len(in.Roles))
+	seen := map[string]bool{}
+	for _, ro := range in.Roles {
+		ro = strings.TrimSpace(ro)
+		if ro != "" && !seen[ro] {
+			seen[ro] = true
+			roles = append(roles, ro)
+		}
+	}
+	if len(roles) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "at least one role required"})
+		return
+	}
+
+	if !h.canGrant(r, roles) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "cannot grant roles with permissions you do not have"})
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	// role lama target juga harus subset: tanpa roles:manage, manager tidak
+	// boleh menurunkan user yang izinnya lebih tinggi darinya
+	if !auth.HasPermission(r.Context(), rolesManage) {
+		u, err := h.Repo.FindByID(id)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+			return
+		}
+		if !h.canGrant(r, u.Roles) {
+			writeJSON(w, http.StatusForbidden, map[string]any{"status": "fail", "message": "cannot change roles of a user with permissions you do not have"})
+			return
+		}
+	}
+	actor, _ := auth.UserIDFromCtx(r.Context())
+	old, err := h.Repo.SetRoles(id, roles, actor)
+	switch err {
+	case nil:
+	case ErrUserNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	case ErrUnknownRole:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "unknown role"})
+		return
+	case ErrLastAdmin:
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": err.Error()})
+		return
+	default:
+		log.Printf("[users.SetRoles] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	// access token lama membawa role lama; paksa refresh (refresh token tetap berlaku)
+	h.revoke(auth.RevokeUser, id)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "success",
+		"data":   map[string]any{"userId": id, "roles": roles, "previous": old},
+	})
+}
+
+// canGrant: pemegang roles:manage boleh memberi/mencabut role apa pun;
+// users:manage saja hanya role yang izinnya subset izin global pemanggil
+// (anti naik pangkat sendiri, mis. memberi diri sendiri admin). Dipakai utk
+// role baru maupun role lama target.
+func (h *Handler) canGrant(r *http.Request, roles []string) bool {
+	if auth.HasPermission(r.Context(), rolesManage) {
+		return true
+	}
+	if h.RolePermissions == nil {
+		return false
+	}
+	mine := h.RolePermissions(auth.RolesFromCtx(r.Context()))
+	for p := range h.RolePermissions(roles) {
+		if !mine[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// GET /admin/roles: registry role.
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Repo.ListRoles()
+	if err != nil {
+		log.Printf("[users.ListRoles] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"roles": list}})
+}
@@ -0,0 +1,105 @@
+package users
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+func sessionInfo(r *http.Request) auth.SessionInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	ua := r.UserAgent()
+	if len(ua) > 512 {
+		ua = ua[:512]
+	}
+	return auth.SessionInfo{UserAgent: ua, IP: ip}
+}
+
+// revoke mencabut access token; gagal dicatat saja karena refresh token-nya
+// sudah dicabut dan access token mati sendiri setelah AccessTTL.
+func (h *Handler) revoke(kind, subject string) error {
+	if h.Revoked == nil {
+		return nil
+	}
+	err := h.Revoked.Revoke(kind, subject)
+	if err != nil {
+		log.Printf("[users.revoke] %s %s: %v", kind, subject, err)
+	}
+	return err
+}
+
+// GET /auth/sessions: sesi login aktif milik pemanggil.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	list, err := h.Refresh.Sessions(uid)
+	if err != nil {
+		log.Printf("[users.ListSessions] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	cur := auth.SessionIDFromCtx(r.Context())
+	for i := range list {
+		list[i].Current = list[i].ID == cur
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"sessions": list}})
+}
+
+// DELETE /auth/sessions/{id}: akhiri satu sesi (refresh + access token-nya).
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	sid := chi.URLParam(r, "id")
+	switch err := h.Refresh.RevokeSession(uid, sid); err {
+	case nil:
+	case auth.ErrSessionNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "session not found"})
+		return
+	default:
+		log.Printf("[users.DeleteSession] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	h.revoke(auth.RevokeSID, sid)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "session revoked"})
+}
+
+// DELETE /auth/sessions: akhiri semua sesi pemanggil, termasuk sesi ini
+// (personal access token ikut dicabut).
+func (h *Handler) DeleteSessions(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	if err := h.revokeAll(uid); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "all sessions revoked"})
+}
+
+// revokeAll: semua refresh token, personal access token + semua access
+// token yang sudah terbit.
+func (h *Handler) revokeAll(uid string) error {
+	if err := h.Refresh.RevokeUser(uid); err != nil {
+		log.Printf("[users.revokeAll] %s: %v", uid, err)
+		return err
+	}
+	return h.revoke(auth.RevokeUser, uid)
+}
+
+// POST /admin/users/{id}/revoke-tokens (admin)
+func (h *Handler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "id")
+	if _, err := h.Repo.FindByID(uid); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+		return
+	}
+	if err := h.revokeAll(uid); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "message": "all tokens revoked"})
+}
@@ -0,0 +1,144 @@
+package users
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/audit"
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+// Backoff: setelah Threshold kegagalan berturut-turut, tiap kegagalan
+// berikutnya mengunci selama Base * 2^(n-Threshold), maksimal Max.
+type Backoff struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+func (b Backoff) Delay(failures int) time.Duration {
+	if failures < b.Threshold {
+		return 0
+	}
+	d := b.Base
+	for i := b.Threshold; i < failures && d < b.Max; i++ {
+		d *= 2
+	}
+	return min(d, b.Max)
+}
+
+// failureWindow: hitungan gagal direset jika tidak ada kegagalan selama ini.
+const failureWindow = 24 * time.Hour
+
+// loginLimiter: hitungan gagal per IP dan re-auth per user di memori (per
+// instance; login akun dan email tak terdaftar dihitung di DB).
+type loginLimiter struct {
+	mu      sync.Mutex
+	entries map[string]*limitEntry
+	swept   time.Time
+}
+
+type limitEntry struct {
+	failures int
+	last     time.Time
+	until    time.Time
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{entries: map[string]*limitEntry{}}
+}
+
+// blocked: sisa waktu kunci utk key (0 = boleh mencoba).
+func (l *loginLimiter) blocked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e := l.entries[key]; e != nil {
+		return time.Until(e.until).Round(time.Second)
+	}
+	return 0
+}
+
+// fail mencatat satu kegagalan dan mengembalikan lama kunci (0 = belum dikunci).
+func (l *loginLimiter) fail(key string, b Backoff) (int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.swept) > time.Minute {
+		for k, e := range l.entries {
+			if now.Sub(e.last) > failureWindow {
+				delete(l.entries, k)
+			}
+		}
+		l.swept = now
+	}
+	e := l.entries[key]
+	if e == nil || now.Sub(e.last) > failureWindow {
+		e = &limitEntry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.last = now
+	d := b.Delay(e.failures)
+	e.until = now.Add(d)
+	return e.failures, d
+}
+
+// tooManyAttempts: 429 + Retry-After (detik).
+func tooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(wait.Seconds()))))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{
+		"status": "fail", "message": "too many failed login attempts, try again later",
+	})
+}
+
+// loginBlocked: sisa kunci utk IP atau akun (0 = boleh mencoba).
+func (h *Handler) loginBlocked(ip string, u *User) time.Duration {
+	d := h.limiter.blocked("ip:" + ip)
+	if u != nil && u.LockedUntil != nil {
+		d = max(d, time.Until(*u.LockedUntil).Round(time.Second))
+	}
+	return d
+}
+
+// loginFailed mencatat kegagalan utk IP dan akun (uid "" = hanya IP, mis.
+// email tak terdaftar yang sudah dihitung lewat Repo.EmailLoginFailed).
+func (h *Handler) loginFailed(ip, uid string) {
+	if n, d := h.limiter.fail("ip:"+ip, h.IPBackoff); d > 0 && n == h.IPBackoff.Threshold {
+		log.Printf("[users.Login] ip %s locked for %s", ip, d)
+		if h.Audit != nil {
+			if err := h.Audit.Record(audit.Entry{
+				Action: "auth.ip.locked", TargetType: "ip", TargetID: ip,
+				Details: map[string]any{"failures": n, "lockedUntil": time.Now().Add(d)},
+			}); err != nil {
+				log.Printf("[users.Login] audit error: %v", err)
+			}
+		}
+	}
+	if uid == "" {
+		return
+	}
+	if until, err := h.Repo.LoginFailed(uid, ip, h.AccountBackoff); err != nil {
+		log.Printf("[users.Login] record failure error: %v", err)
+	} else if until != nil {
+		log.Printf("[users.Login] user %s locked until %s", uid, until.Format(time.RFC3339))
+	}
+}
+
+// POST /admin/users/{id}/unlock
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	actor, _ := auth.UserIDFromCtx(r.Context())
+	switch err := h.Repo.Unlock(chi.URLParam(r, "id"), actor); err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success"})
+	case ErrUserNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "user not found"})
+	default:
+		log.Printf("[users.UnlockUser] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
@@ -0,0 +1,48 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Threshold: 5, Base: 30 * time.Second, Max: 15 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{7, 2 * time.Minute},
+		{9, 8 * time.Minute},
+		{10, 15 * time.Minute}, // 16m dipotong ke Max
+		{1000, 15 * time.Minute},
+	}
+	for _, tc := range tests {
+		if got := b.Delay(tc.failures); got != tc.want {
+			t.Errorf("Delay(%d) = %s, want %s", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestLoginLimiter(t *testing.T) {
+	l := newLoginLimiter()
+	b := Backoff{Threshold: 2, Base: time.Minute, Max: time.Hour}
+	if n, d := l.fail("ip:1.2.3.4", b); n != 1 || d != 0 {
+		t.Fatalf("first failure: n=%d d=%s", n, d)
+	}
+	if d := l.blocked("ip:1.2.3.4"); d > 0 {
+		t.Fatalf("blocked after 1 failure: %s", d)
+	}
+	if n, d := l.fail("ip:1.2.3.4", b); n != 2 || d != time.Minute {
+		t.Fatalf("second failure: n=%d d=%s", n, d)
+	}
+	if d := l.blocked("ip:1.2.3.4"); d <= 0 {
+		t.Error("want blocked after threshold")
+	}
+	if d := l.blocked("ip:5.6.7.8"); d != 0 {
+		t.Errorf("other key blocked: %s", d)
+	}
+}
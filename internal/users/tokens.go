@@ -0,0 +1,110 @@
+package users
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ImamSR/go-books-api/internal/auth"
+)
+
+const (
+	patDefaultDays = 90
+	patMaxDays     = 365
+	patMaxPerUser  = 50
+)
+
+// POST /users/me/tokens {name, scopes, expiresInDays}
+// Scope = nama izin (mis. books:read) yang dimiliki pemanggil saat ini.
+// Token plaintext hanya ada di respons ini.
+func (h *Handler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expiresInDays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "invalid json"})
+		return
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || len(in.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "name required (max 100 chars)"})
+		return
+	}
+	if len(in.Scopes) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "at least one scope required"})
+		return
+	}
+	slices.Sort(in.Scopes)
+	in.Scopes = slices.Compact(in.Scopes)
+	for _, s := range in.Scopes {
+		if !auth.HasPermission(r.Context(), s) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "scope not granted: " + s})
+			return
+		}
+	}
+	switch {
+	case in.ExpiresInDays == 0:
+		in.ExpiresInDays = patDefaultDays
+	case in.ExpiresInDays < 0 || in.ExpiresInDays > patMaxDays:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"status": "fail", "message": "expiresInDays must be 1..365"})
+		return
+	}
+
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	list, err := h.PATs.List(uid)
+	if err != nil {
+		log.Printf("[users.CreateToken] list error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if len(list) >= patMaxPerUser {
+		writeJSON(w, http.StatusConflict, map[string]any{"status": "fail", "message": "too many tokens, revoke unused ones first"})
+		return
+	}
+	p, token, err := h.PATs.Create(uid, in.Name, in.Scopes, time.Now().AddDate(0, 0, in.ExpiresInDays))
+	if err != nil {
+		log.Printf("[users.CreateToken] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status": "success",
+		"data":   map[string]any{"token": token, "info": p},
+	})
+}
+
+// GET /users/me/tokens
+func (h *Handler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	list, err := h.PATs.List(uid)
+	if err != nil {
+		log.Printf("[users.ListTokens] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+		return
+	}
+	if list == nil {
+		list = []auth.PAT{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"tokens": list}})
+}
+
+// DELETE /users/me/tokens/{id}: berlaku langsung (token dicek ke DB tiap request).
+func (h *Handler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromCtx(r.Context())
+	switch err := h.PATs.Revoke(uid, chi.URLParam(r, "id")); err {
+	case nil:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success"})
+	case auth.ErrPATNotFound:
+		writeJSON(w, http.StatusNotFound, map[string]any{"status": "fail", "message": "token not found"})
+	default:
+		log.Printf("[users.DeleteToken] error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"status": "error"})
+	}
+}
@@ -2,15 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/ImamSR/go-books-api/internal/audit"
 	"github.com/ImamSR/go-books-api/internal/books"
 	"github.com/ImamSR/go-books-api/internal/db"
     "github.com/ImamSR/go-books-api/internal/httpx"
 	"github.com/ImamSR/go-books-api/internal/auth"
+	"github.com/ImamSR/go-books-api/internal/mail"
+	"github.com/ImamSR/go-books-api/internal/oidc"
+	"github.com/ImamSR/go-books-api/internal/orgs"
+	"github.com/ImamSR/go-books-api/internal/password"
+	"github.com/ImamSR/go-books-api/internal/rbac"
+	"github.com/ImamSR/go-books-api/internal/totp"
 	"github.com/ImamSR/go-books-api/internal/users"
 )
 
@@ -22,15 +34,125 @@ func main() {
 	}
 
 	// books
-	bookStore := books.NewPGStore(pool)
+	// BOOKS_RLS=1: role DB tunduk pada policy RLS books (migrations/optional/books_rls.up.sql)
+	bookStore := books.NewPGStore(pool, os.Getenv("BOOKS_RLS") == "1")
 	bh := books.NewHandler(bookStore)
+	bh.RequireIfMatch = os.Getenv("REQUIRE_IF_MATCH") == "1"
 
 	// users
 	userRepo := users.NewPGRepo(pool)
-	tokenGen := auth.NewTokenGenerator(auth.MustJWTSecret())
-	uh := users.NewHandler(userRepo, tokenGen)
+	keys := auth.MustKeySet()
+	tokenGen := auth.NewTokenGenerator(keys)
+	revoked := auth.NewRevocations(pool)
+	if err := revoked.Start(ctx, 30*time.Second); err != nil {
+		log.Fatal(err)
+	}
+	mailer, err := mail.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	uh := users.NewHandler(userRepo, tokenGen, auth.NewPGRefreshStore(pool), revoked, mailer)
+	if v := os.Getenv("APP_BASE_URL"); v != "" {
+		uh.BaseURL = strings.TrimRight(v, "/")
+	}
+	uh.RequireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "1"
+	uh.MFA = auth.NewMFATokens(keys)
+	uh.PATs = auth.NewPGPATStore(pool)
+	if uh.Cookies, err = auth.CookiesFromEnv(); err != nil {
+		log.Fatal(err)
+	} else if uh.Cookies != nil && !uh.Cookies.Secure {
+		log.Println("warning: session cookies without Secure flag (COOKIE_SECURE=0)")
+	}
+	if uh.Passwords, err = password.FromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if uh.Policy, err = password.PolicyFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	auditLog := audit.NewPGLog(pool)
+	if cfg, ok, err := oidc.ConfigFromEnv(); err != nil {
+		log.Fatal(err)
+	} else if ok {
+		if uh.OIDC, err = oidc.NewProvider(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("oidc login enabled:", cfg.Issuer)
+	}
+	uh.Audit = auditLog
+	if uh.TOTPKey, err = totp.CipherFromEnv(); errors.Is(err, totp.ErrNoKey) {
+		// secret 2FA yang sudah ada tidak bisa dibuka tanpa kunci
+		if inUse, err := userRepo.TOTPInUse(); err != nil {
+			log.Fatal(err)
+		} else if inUse {
+			log.Fatal("users with 2FA enabled exist but TOTP_ENC_KEY is not set")
+		}
+		log.Println("2FA disabled: TOTP_ENC_KEY not set")
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	if v := os.Getenv("TOTP_ISSUER"); v != "" {
+		uh.TOTPIssuer = v
+	}
+	if v := os.Getenv("DEFAULT_ROLE"); v != "" {
+		uh.DefaultRole = v
+	}
+	if roles, err := userRepo.ListRoles(); err != nil {
+		log.Fatal(err)
+	} else if !slices.ContainsFunc(roles, func(r users.Role) bool { return r.Name == uh.DefaultRole }) {
+		log.Fatalf("DEFAULT_ROLE %q is not in the roles registry", uh.DefaultRole)
+	}
+	// admin pertama: user dgn email ini (sekarang atau saat register nanti)
+	if email := os.Getenv("BOOTSTRAP_ADMIN_EMAIL"); email != "" {
+		uh.BootstrapAdminEmail = email
+		if ok, err := userRepo.BootstrapAdmin(email); err != nil {
+			log.Fatal(err)
+		} else if ok {
+			log.Println("bootstrapped first admin:", email)
+		}
+	}
+
+	// organisasi: undangan memakai mailer & BaseURL yang sama dgn users
+	oh := orgs.NewHandler(orgs.NewPGStore(pool), mailer)
+	oh.BaseURL, oh.DefaultRole = uh.BaseURL, uh.DefaultRole
+
+	// permissions: role -> izin dari DB, dimuat ulang berkala
+	rbacStore := rbac.NewPGStore(pool)
+	perms := rbac.NewResolver(rbacStore)
+	if err := perms.Start(ctx, 30*time.Second); err != nil {
+		log.Fatal(err)
+	}
+	uh.RolePermissions = perms.Permissions
+
+	proxies, err := httpx.TrustedProxiesFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	router := httpx.NewRouter(httpx.Deps{
+		Books:          bh,
+		Users:          uh,
+		Orgs:           oh,
+		Audit:          auditLog,
+		RBAC:           rbac.NewHandler(rbacStore, perms),
+		Perms:          perms,
+		Keys:           keys,
+		Revoked:        revoked,
+		PATs:           uh.PATs,
+		Cookies:        uh.Cookies,
+		TrustedProxies: proxies,
+	})
 
-	router := httpx.NewRouter(bh, uh)
+	// SIGHUP: baca ulang JWT_KEYS_DIR (rotasi kunci tanpa restart)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := keys.Reload(); err != nil {
+				log.Printf("reload jwt keys: %v", err)
+				continue
+			}
+			log.Println("jwt keys reloaded")
+		}
+	}()
 
 	addr := ":8080"
 	if v := os.Getenv("PORT"); v != "" { addr = ":" + v }
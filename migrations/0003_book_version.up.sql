@@ -0,0 +1,2 @@
+-- versi baris utk optimistic concurrency (ETag / If-Match)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reading_sessions (
+  id          TEXT PRIMARY KEY,
+  book_id     TEXT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  user_id     TEXT REFERENCES users(id) ON DELETE SET NULL,
+  start_page  INT  NOT NULL CHECK (start_page >= 0),
+  end_page    INT  NOT NULL CHECK (end_page >= start_page),
+  started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ended_at    TIMESTAMPTZ CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+-- sesi terbaru per buku (read_page/reading diturunkan dari sini)
+CREATE INDEX IF NOT EXISTS idx_reading_sessions_book ON reading_sessions (book_id, started_at DESC);
@@ -0,0 +1,14 @@
+ALTER TABLE books
+  ADD COLUMN IF NOT EXISTS read_page INT NOT NULL DEFAULT 0 CHECK (read_page >= 0 AND read_page <= page_count),
+  ADD COLUMN IF NOT EXISTS reading   BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS finished  BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE books b
+   SET read_page = p.read_page, reading = p.reading, finished = p.finished
+  FROM book_progress p
+ WHERE p.book_id = b.id AND p.user_id = b.owner_id;
+CREATE INDEX IF NOT EXISTS idx_books_reading  ON books (reading);
+CREATE INDEX IF NOT EXISTS idx_books_finished ON books (finished);
+
+DROP TABLE IF EXISTS book_progress;
+DROP INDEX IF EXISTS idx_books_owner;
+ALTER TABLE books DROP COLUMN IF EXISTS owner_id;
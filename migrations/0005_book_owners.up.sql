@@ -0,0 +1,36 @@
+-- pemilik buku = pembuatnya (JWT sub)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS owner_id TEXT REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_books_owner ON books (owner_id);
+
+-- buku lama: pemilik = admin pertama (atau user pertama jika belum ada admin)
+UPDATE books SET owner_id = (
+  SELECT id FROM users
+   ORDER BY ('admin' = ANY(roles)) DESC, created_at, id
+   LIMIT 1
+) WHERE owner_id IS NULL;
+
+-- state baca per user; satu buku bisa dilacak banyak user
+CREATE TABLE IF NOT EXISTS book_progress (
+  book_id     TEXT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  read_page   INT  NOT NULL DEFAULT 0 CHECK (read_page >= 0),
+  reading     BOOLEAN NOT NULL DEFAULT FALSE,
+  finished    BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (book_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_book_progress_user ON book_progress (user_id);
+
+-- state baca lama pindah ke pemiliknya
+INSERT INTO book_progress (book_id, user_id, read_page, reading, finished, updated_at)
+SELECT id, owner_id, read_page, reading, finished, updated_at
+  FROM books
+ WHERE owner_id IS NOT NULL
+ON CONFLICT (book_id, user_id) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_books_reading;
+DROP INDEX IF EXISTS idx_books_finished;
+ALTER TABLE books
+  DROP COLUMN IF EXISTS read_page,
+  DROP COLUMN IF EXISTS reading,
+  DROP COLUMN IF EXISTS finished;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_books_search;
+ALTER TABLE books DROP COLUMN IF EXISTS search;
@@ -0,0 +1,9 @@
+-- full-text search: name (A) > author (B) > publisher (C); config 'simple'
+-- supaya token sama dengan tokenizer memStore (tanpa stemming)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce(author, '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce(publisher, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_books_search ON books USING GIN (search);
@@ -0,0 +1,15 @@
+-- refresh token disimpan sbg hash (sha256); satu family = satu rantai rotasi
+-- sejak login. Token lama yang dipakai ulang -> seluruh family dicabut.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id          TEXT PRIMARY KEY,
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id   TEXT NOT NULL,
+  parent_id   TEXT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+  token_hash  TEXT NOT NULL UNIQUE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at  TIMESTAMPTZ NOT NULL,
+  used_at     TIMESTAMPTZ,
+  revoked_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS token_revocations;
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_session;
+DROP TABLE IF EXISTS auth_sessions;
@@ -0,0 +1,36 @@
+-- sesi login = satu family refresh token; dipakai utk GET/DELETE /auth/sessions
+CREATE TABLE IF NOT EXISTS auth_sessions (
+  id            TEXT PRIMARY KEY,
+  user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  user_agent    TEXT NOT NULL DEFAULT '',
+  ip            TEXT NOT NULL DEFAULT '',
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  revoked_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions (user_id);
+
+INSERT INTO auth_sessions (id, user_id, created_at, last_used_at, revoked_at)
+SELECT family_id, MIN(user_id), MIN(created_at), MAX(created_at),
+       CASE WHEN bool_and(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE refresh_tokens
+  ADD CONSTRAINT fk_refresh_tokens_session
+  FOREIGN KEY (family_id) REFERENCES auth_sessions(id) ON DELETE CASCADE;
+
+-- access token yang dicabut sebelum exp:
+--   sid  = semua token satu sesi,
+--   user = semua token user dengan iat <= revoked_at.
+-- expires_at = revoked_at + umur access token; setelah itu baris boleh dihapus.
+CREATE TABLE IF NOT EXISTS token_revocations (
+  kind        TEXT NOT NULL CHECK (kind IN ('sid', 'user')),
+  subject     TEXT NOT NULL,
+  revoked_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at  TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (kind, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_token_revocations_revoked ON token_revocations (revoked_at);
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires ON token_revocations (expires_at);
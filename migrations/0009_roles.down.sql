@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{}';
+UPDATE users u SET roles = COALESCE(
+  (SELECT array_agg(ur.role ORDER BY ur.role) FROM user_roles ur WHERE ur.user_id = u.id), '{}');
+ALTER TABLE users ALTER COLUMN roles DROP DEFAULT;
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,42 @@
+-- registry role: nama role di user_roles harus terdaftar di sini
+CREATE TABLE IF NOT EXISTS roles (
+  name         TEXT PRIMARY KEY,
+  description  TEXT NOT NULL DEFAULT '',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+INSERT INTO roles (name, description) VALUES
+  ('reader', 'Read books in own library'),
+  ('editor', 'Create, edit and delete own books'),
+  ('admin',  'Full access, manage users and roles')
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role        TEXT NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+  granted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  granted_by  TEXT REFERENCES users(id) ON DELETE SET NULL,
+  PRIMARY KEY (user_id, role)
+);
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles (role);
+
+-- pindahkan users.roles (TEXT[] bebas); nama yang tidak terdaftar dibuang
+INSERT INTO user_roles (user_id, role)
+SELECT u.id, r.name
+FROM users u CROSS JOIN LATERAL unnest(u.roles) AS x(role)
+JOIN roles r ON r.name = x.role
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
+
+-- jejak aksi sensitif (perubahan role, dll)
+CREATE TABLE IF NOT EXISTS audit_log (
+  id           BIGSERIAL PRIMARY KEY,
+  actor_id     TEXT,
+  action       TEXT NOT NULL,
+  target_type  TEXT NOT NULL DEFAULT '',
+  target_id    TEXT NOT NULL DEFAULT '',
+  details      JSONB NOT NULL DEFAULT '{}',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log (created_at DESC);
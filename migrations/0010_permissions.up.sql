@@ -0,0 +1,35 @@
+-- izin granular; role -> izin diatur lewat /admin/roles/{role}/permissions
+CREATE TABLE IF NOT EXISTS permissions (
+  name         TEXT PRIMARY KEY,
+  description  TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO permissions (name, description) VALUES
+  ('books:read',    'List and view books'),
+  ('books:export',  'Export books'),
+  ('books:create',  'Create books'),
+  ('books:import',  'Bulk import books'),
+  ('books:update',  'Update books'),
+  ('books:delete',  'Delete books'),
+  ('books:all',     'See and modify every user''s books'),
+  ('reading:write', 'Record reading sessions'),
+  ('users:manage',  'Assign roles and revoke user tokens'),
+  ('roles:manage',  'Create roles and edit role permissions'),
+  ('audit:read',    'Read the audit log')
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role        TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+  permission  TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE ON UPDATE CASCADE,
+  PRIMARY KEY (role, permission)
+);
+
+-- sama dengan daftar role yang sebelumnya di-hardcode di router (hapus: admin saja)
+INSERT INTO role_permissions (role, permission) VALUES
+  ('reader', 'books:read'), ('reader', 'books:export'),
+  ('editor', 'books:read'), ('editor', 'books:export'), ('editor', 'books:create'),
+  ('editor', 'books:import'), ('editor', 'books:update'),
+  ('editor', 'reading:write')
+ON CONFLICT DO NOTHING;
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+-- akun lama dianggap terverifikasi supaya REQUIRE_VERIFIED_EMAIL tidak mengunci mereka
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+-- token sekali pakai utk reset password / verifikasi email (hanya hash disimpan)
+CREATE TABLE IF NOT EXISTS user_tokens (
+  id          TEXT PRIMARY KEY,
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose     TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verify')),
+  token_hash  TEXT NOT NULL UNIQUE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at  TIMESTAMPTZ NOT NULL,
+  used_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens (user_id, purpose);
@@ -0,0 +1,14 @@
+-- 2FA: secret TOTP terenkripsi (AES-GCM, lihat internal/totp); aktif setelah dikonfirmasi
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret     BYTEA,
+  ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS totp_last_step  BIGINT NOT NULL DEFAULT 0; -- step terakhir yang dipakai (anti replay)
+
+-- recovery code sekali pakai (hanya hash disimpan)
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+  user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash  TEXT NOT NULL,
+  used_at    TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, code_hash)
+);
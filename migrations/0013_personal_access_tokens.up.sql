@@ -0,0 +1,14 @@
+-- personal access token utk skrip/CI (hanya hash disimpan)
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+  id            TEXT PRIMARY KEY,
+  user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name          TEXT NOT NULL,
+  prefix        TEXT NOT NULL,
+  token_hash    TEXT NOT NULL UNIQUE,
+  scopes        TEXT[] NOT NULL DEFAULT '{}',
+  expires_at    TIMESTAMPTZ NOT NULL,
+  last_used_at  TIMESTAMPTZ,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  revoked_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_pat_user ON personal_access_tokens (user_id);
@@ -0,0 +1,5 @@
+-- brute-force: gagal login berturut-turut per akun + kunci sementara
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS failed_logins       INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS locked_until        TIMESTAMPTZ;
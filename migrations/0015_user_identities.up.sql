@@ -0,0 +1,11 @@
+-- akun IdP (OIDC) yang tertaut ke user lokal
+CREATE TABLE IF NOT EXISTS user_identities (
+  issuer         TEXT NOT NULL,
+  subject        TEXT NOT NULL,
+  user_id        TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email          TEXT NOT NULL DEFAULT '',
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_login_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (issuer, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS avatar_url   TEXT NOT NULL DEFAULT '';
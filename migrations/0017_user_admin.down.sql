@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_created;
+ALTER TABLE users
+  DROP COLUMN IF EXISTS password_reset_required,
+  DROP COLUMN IF EXISTS deleted_at,
+  DROP COLUMN IF EXISTS suspend_reason,
+  DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,7 @@
+-- admin: suspend, soft delete, paksa reset password
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS suspended_at            TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS suspend_reason          TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS deleted_at              TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_users_created ON users (created_at DESC, id);
@@ -0,0 +1,10 @@
+-- jika optional/books_rls sempat dipasang: policy memakai org_id
+DROP POLICY IF EXISTS books_tenant ON books;
+ALTER TABLE books DISABLE ROW LEVEL SECURITY;
+DELETE FROM permissions WHERE name = 'orgs:manage';
+DROP INDEX IF EXISTS idx_books_org;
+-- buku org tidak dihapus: kembali jadi buku pribadi pemiliknya
+ALTER TABLE books DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS org_invitations;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
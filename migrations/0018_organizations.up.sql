@@ -0,0 +1,43 @@
+-- organisasi (workspace tenant); keanggotaan dgn role per org dari registry roles
+CREATE TABLE IF NOT EXISTS organizations (
+  id          TEXT PRIMARY KEY,
+  name        TEXT NOT NULL,
+  created_by  TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+  org_id     TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role       TEXT NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+  joined_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_org_members_user ON org_members (user_id);
+
+-- undangan lewat email (hanya hash token disimpan)
+CREATE TABLE IF NOT EXISTS org_invitations (
+  id           TEXT PRIMARY KEY,
+  org_id       TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email        TEXT NOT NULL,
+  role         TEXT NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+  token_hash   TEXT NOT NULL UNIQUE,
+  invited_by   TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at   TIMESTAMPTZ NOT NULL,
+  accepted_at  TIMESTAMPTZ,
+  accepted_by  TEXT REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_org_invitations_org ON org_invitations (org_id, created_at DESC);
+
+-- tenant buku: NULL = ruang pribadi (semua buku lama)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS org_id TEXT REFERENCES organizations(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_books_org ON books ((COALESCE(org_id, '')));
+
+INSERT INTO permissions (name, description) VALUES
+  ('orgs:manage', 'Invite, remove and change roles of organization members')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'orgs:manage')
+ON CONFLICT DO NOTHING;
+
+-- RLS books: opt-in terpisah, lihat migrations/optional/books_rls.up.sql
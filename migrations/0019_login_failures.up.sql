@@ -0,0 +1,9 @@
+-- brute-force: gagal login utk email tak terdaftar (key = sha256 email),
+-- dihitung di DB seperti akun agar berlaku di semua instance
+CREATE TABLE IF NOT EXISTS login_failures (
+  email_hash     TEXT PRIMARY KEY,
+  failures       INT NOT NULL DEFAULT 0,
+  last_failed_at TIMESTAMPTZ NOT NULL,
+  locked_until   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_login_failures_last ON login_failures (last_failed_at);
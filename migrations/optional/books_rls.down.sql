@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS books_tenant ON books;
+ALTER TABLE books DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,10 @@
+-- RLS books (opt-in, tidak ikut dijalankan migrate): lapisan kedua isolasi
+-- tenant di samping filter org_id di query. Hanya berlaku bagi role DB yang
+-- bukan pemilik tabel; jalankan manual setelah 0018 lalu set BOOKS_RLS=1
+-- agar app mengisi app.org_id per transaksi:
+--   psql "$DATABASE_URL" -f migrations/optional/books_rls.up.sql
+ALTER TABLE books ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS books_tenant ON books;
+CREATE POLICY books_tenant ON books
+  USING (COALESCE(org_id, '') = COALESCE(current_setting('app.org_id', true), ''))
+  WITH CHECK (COALESCE(org_id, '') = COALESCE(current_setting('app.org_id', true), ''));